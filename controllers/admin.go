@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/enzdor/gomsg/models"
+	"github.com/enzdor/gomsg/sqlc"
+	"github.com/enzdor/gomsg/utils"
+)
+
+func (h *Handler) ServeAdmin(w http.ResponseWriter, r *http.Request) {
+	tmpl := utils.Serve("admin")
+	ps := strings.Split(r.URL.Path, "/")
+
+	if len(ps) > 2 && ps[2] != "" {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusNotFound), http.StatusSeeOther)
+	    return
+	}
+
+	if !h.isAdmin(r) {
+	    h.serveUnauthorized(w)
+	    return
+	}
+
+	boards, err := h.q.ListBoards(context.Background())
+	if err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
+	    return
+	}
+
+	bans, err := h.q.ListBannedHashes(context.Background())
+	if err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
+	    return
+	}
+
+	token, err := h.csrfToken(w, r)
+	if err != nil {
+	    log.Print(err)
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
+	    return
+	}
+
+	switch r.Method {
+	case "GET":
+	    h.serveAdminPage(w, tmpl, "", models.FormError{Bool: false, Message: "", Field: "name"}, boards, bans, token)
+	    return
+
+	case "POST":
+	    if err := r.ParseForm(); err != nil {
+		http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusBadRequest), http.StatusSeeOther)
+		return
+	    }
+
+	    if !h.checkCSRF(r) {
+		http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusForbidden), http.StatusSeeOther)
+		return
+	    }
+
+	    action := r.FormValue("action")
+	    name := r.FormValue("name")
+
+	    var id int32
+	    if action != "create" && action != "ban" && action != "unban" {
+		board, err := h.adminBoard(r.FormValue("board_id"))
+		if err != nil {
+		    http.Redirect(w, r, "/error/" + strconv.Itoa(utils.ErrorStatus(err)), http.StatusSeeOther)
+		    return
+		}
+		id = board.BoardID
+	    }
+
+	    if action == "create" || action == "rename" {
+		error, err := utils.ValidateBoardName(name)
+		if err == nil {
+		    if _, err := h.q.GetBoardByName(context.Background(), name); err == nil {
+			error = models.FormError{Bool: true, Message: "A board with this name already exists", Field: "name"}
+		    }
+		}
+
+		if error.Bool {
+		    h.serveAdminPage(w, tmpl, name, error, boards, bans, token)
+		    return
+		}
+	    }
+
+	    limits := sqlc.UpdateBoardLimitsParams{}
+	    if action == "limits" {
+		params, error, err := utils.ValidateBoardLimits(r.FormValue("max_threads"), r.FormValue("max_replies"), r.FormValue("bump_limit"), r.FormValue("max_comment"))
+		if err != nil {
+		    h.serveAdminPage(w, tmpl, "", error, boards, bans, token)
+		    return
+		}
+		limits = params
+		limits.BoardID = id
+	    }
+
+	    links := sqlc.UpdateBoardLinksParams{}
+	    if action == "links" {
+		params, error, err := utils.ValidateBoardLinks(r.FormValue("links_enabled") != "", r.FormValue("allowed_domains"), r.FormValue("denied_domains"))
+		if err != nil {
+		    h.serveAdminPage(w, tmpl, "", error, boards, bans, token)
+		    return
+		}
+		links = params
+		links.BoardID = id
+	    }
+
+	    files := sqlc.UpdateBoardFilesParams{}
+	    if action == "files" {
+		params, error, err := utils.ValidateBoardFiles(r.FormValue("max_file_size"), r.FormValue("allowed_types"), r.FormValue("duplicate_window"))
+		if err != nil {
+		    h.serveAdminPage(w, tmpl, "", error, boards, bans, token)
+		    return
+		}
+		files = params
+		files.BoardID = id
+	    }
+
+	    rates := sqlc.UpdateBoardRatesParams{}
+	    if action == "rates" {
+		params, error, err := utils.ValidateBoardRates(r.FormValue("thread_burst"), r.FormValue("thread_interval"), r.FormValue("reply_burst"), r.FormValue("reply_interval"))
+		if err != nil {
+		    h.serveAdminPage(w, tmpl, "", error, boards, bans, token)
+		    return
+		}
+		rates = params
+		rates.BoardID = id
+	    }
+
+	    ban := sqlc.CreateBannedHashParams{}
+	    if action == "ban" {
+		params, error, err := h.validateBan(r.FormValue("sha256"), r.FormValue("reason"))
+		if err != nil {
+		    if !error.Bool {
+			log.Print(err)
+			http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
+			return
+		    }
+		    h.serveAdminPage(w, tmpl, "", error, boards, bans, token)
+		    return
+		}
+		ban = params
+	    }
+
+	    switch action {
+	    case "create":
+		_, err = h.q.CreateBoard(context.Background(), name)
+	    case "rename":
+		_, err = h.q.RenameBoard(context.Background(), sqlc.RenameBoardParams{Name: name, BoardID: id})
+	    case "archive", "unarchive":
+		_, err = h.q.SetBoardArchived(context.Background(), sqlc.SetBoardArchivedParams{
+		    Archived: action == "archive",
+		    BoardID: id,
+		})
+	    case "limits":
+		_, err = h.q.UpdateBoardLimits(context.Background(), limits)
+	    case "links":
+		_, err = h.q.UpdateBoardLinks(context.Background(), links)
+	    case "files":
+		_, err = h.q.UpdateBoardFiles(context.Background(), files)
+	    case "rates":
+		_, err = h.q.UpdateBoardRates(context.Background(), rates)
+	    case "anon":
+		_, err = h.q.SetBoardForceAnon(context.Background(), sqlc.SetBoardForceAnonParams{
+		    ForceAnon: r.FormValue("force_anon") != "",
+		    BoardID: id,
+		})
+	    case "ids":
+		_, err = h.q.SetBoardPosterIDs(context.Background(), sqlc.SetBoardPosterIDsParams{
+		    PosterIds: r.FormValue("poster_ids") != "",
+		    BoardID: id,
+		})
+	    case "ban":
+		_, err = h.q.CreateBannedHash(context.Background(), ban)
+	    case "unban":
+		_, err = h.q.DeleteBannedHash(context.Background(), r.FormValue("sha256"))
+	    case "delete":
+		// the checkbox is required by the form, but a board and all its
+		// threads are too much to lose to a request sent without it
+		if r.PostFormValue("confirm") != "on" {
+		    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusBadRequest), http.StatusSeeOther)
+		    return
+		}
+		err = h.deleteBoard(id)
+	    default:
+		http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusNotFound), http.StatusSeeOther)
+		return
+	    }
+
+	    if err != nil {
+		log.Print(err)
+		http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
+		return
+	    }
+
+	    http.Redirect(w, r, "/admin/", http.StatusSeeOther)
+	    return
+	}
+}
+
+// deleteBoard deletes a board with every thread on it. The names of the
+// files of its attachments are collected in the same transaction and the
+// files removed once it has been committed.
+func (h *Handler) deleteBoard(boardID int32) error {
+	names := []string{}
+
+	err := h.withTx(context.Background(), func(q *sqlc.Queries) error {
+	    if _, err := q.GetBoardForUpdate(context.Background(), boardID); err != nil {
+		return err
+	    }
+
+	    attachments, err := q.GetAllBoardAttachments(context.Background(), boardID)
+	    if err != nil {
+		return err
+	    }
+	    names = attachmentFiles(attachments)
+
+	    _, err = q.DeleteBoard(context.Background(), boardID)
+	    return err
+	})
+	if err != nil {
+	    return err
+	}
+
+	h.removeFiles(names)
+	return nil
+}
+
+func (h *Handler) adminBoard(value string) (sqlc.Board, error) {
+	id, err := strconv.Atoi(value)
+	if err != nil {
+	    return sqlc.Board{}, &models.PathError{Message: "Not found"}
+	}
+
+	return h.q.GetBoard(context.Background(), int32(id))
+}
+
+// validateBan checks a hash to ban, which can also be given as the name of
+// an uploaded file or its thumbnail so moderators do not have to hash the
+// file themselves.
+func (h *Handler) validateBan(value string, reason string) (sqlc.CreateBannedHashParams, models.FormError, error) {
+	name := strings.TrimPrefix(strings.TrimSpace(value), "/files/")
+	if fileNameRegexp.MatchString(name) {
+	    attachment, err := h.q.GetAttachment(context.Background(), sqlc.GetAttachmentParams{FileName: name, ThumbName: name})
+	    if errors.Is(err, sql.ErrNoRows) {
+		error := models.FormError{Bool: true, Message: "There is no file with this name", Field: "ban"}
+		return sqlc.CreateBannedHashParams{}, error, &models.ValidateError{Message: "The hash has not passed the required validation rules."}
+	    }
+	    if err != nil {
+		return sqlc.CreateBannedHashParams{}, models.FormError{Bool: false, Message: "", Field: "ban"}, err
+	    }
+	    value = attachment.Sha256
+	}
+
+	params, error, err := utils.ValidateBannedHash(value, reason)
+	if err != nil {
+	    return params, error, err
+	}
+
+	if _, err := h.q.GetBannedHash(context.Background(), params.Sha256); err == nil {
+	    error = models.FormError{Bool: true, Message: "This hash is already banned", Field: "ban"}
+	    return params, error, &models.ValidateError{Message: "The hash has not passed the required validation rules."}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+	    return params, error, err
+	}
+	params.Date = time.Now()
+
+	return params, error, nil
+}
+
+// serveAdminPage renders the admin page, with the form error of an action
+// that did not pass validation if there is one.
+func (h *Handler) serveAdminPage(w http.ResponseWriter, tmpl *template.Template, name string, error models.FormError, boards []sqlc.Board, bans []sqlc.BannedHash, token string) {
+	data := models.AdminData{
+	    Name: name,
+	    Error: error,
+	    Boards: boards,
+	    BannedHashes: bans,
+	    CSRFToken: token,
+	}
+	tmpl.ExecuteTemplate(w, "layout", data)
+}
+
+// serveUnauthorized answers with a real 401 instead of redirecting to the
+// error page, so that browsers ask for the admin credentials.
+func (h *Handler) serveUnauthorized(w http.ResponseWriter) {
+	tmpl := utils.Serve("error")
+
+	data := utils.CreateErrorData(http.StatusUnauthorized)
+	if boards, err := h.q.ListBoards(context.Background()); err == nil {
+	    data.Boards = boards
+	}
+
+	w.Header().Set("WWW-Authenticate", `Basic realm="GOmsg admin", charset="UTF-8"`)
+	w.WriteHeader(http.StatusUnauthorized)
+	tmpl.ExecuteTemplate(w, "layout", data)
+}
@@ -0,0 +1,301 @@
+package controllers
+
+import (
+    "bytes"
+    "context"
+    "database/sql"
+    "errors"
+    "image"
+    "image/png"
+    "io/ioutil"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "testing"
+
+    "github.com/enzdor/gomsg/models"
+    "github.com/enzdor/gomsg/utils"
+)
+
+func adminRequest(method string, form url.Values) *http.Request {
+    req := httptest.NewRequest(method, "/admin/", strings.NewReader(form.Encode()))
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    req.SetBasicAuth(testAdminUser, testAdminPass)
+
+    return csrfRequest(req)
+}
+
+func TestServeAdmin(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    t.Run("unauthorized", func(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/admin/", nil)
+	req.SetBasicAuth("not the admin", "not the password")
+
+	Th.ServeAdmin(w, req)
+	res := w.Result()
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusUnauthorized {
+	    t.Errorf("expected status %d, got %d", http.StatusUnauthorized, res.StatusCode)
+	}
+	if res.Header.Get("WWW-Authenticate") == "" {
+	    t.Errorf("expected a WWW-Authenticate header")
+	}
+    })
+
+    t.Run("get", func(t *testing.T) {
+	boards, err := Th.q.ListBoards(context.Background())
+	if err != nil {
+	    t.Errorf("expected no error, got %v", err)
+	}
+
+	ts, err := stringTemplate(utils.Serve("admin"), models.AdminData{
+	    Name: "",
+	    Error: models.FormError{Bool: false, Message: "", Field: "name"},
+	    Boards: boards,
+	    CSRFToken: testToken,
+	})
+	if err != nil {
+	    t.Errorf("Expected no errors, got %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	Th.ServeAdmin(w, adminRequest(http.MethodGet, url.Values{}))
+	res := w.Result()
+	defer res.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+	    t.Errorf("Expected no error, got %v", err)
+	}
+
+	if string(responseBody) != ts {
+	    t.Errorf("expected response to be equal to template string")
+	}
+    })
+
+    t.Run("create", func(t *testing.T) {
+	w := httptest.NewRecorder()
+	Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"create"}, "name": {"music"}}))
+	res := w.Result()
+	defer res.Body.Close()
+
+	url, err := res.Location()
+	if err != nil {
+	    t.Errorf("Expected no errors, got %v", err)
+	}
+	if url.Path != "/admin/" {
+	    t.Errorf("expected path to be /admin/ but got " + url.Path)
+	}
+
+	if _, err := Th.q.GetBoardByName(context.Background(), "music"); err != nil {
+	    t.Errorf("expected board music to exist, got %v", err)
+	}
+    })
+
+    t.Run("create with errors", func(t *testing.T) {
+	testCases := []struct {
+	    name    string
+	    board   string
+	    message string
+	}{
+	    {name: "empty", board: "", message: "This field is required"},
+	    {name: "invalid", board: "Not A Board", message: "Board names can only contain up to 100 lowercase letters and numbers"},
+	    {name: "duplicate", board: "music", message: "A board with this name already exists"},
+	}
+
+	for _, tc := range testCases {
+	    w := httptest.NewRecorder()
+	    Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"create"}, "name": {tc.board}}))
+
+	    if !strings.Contains(w.Body.String(), tc.message) {
+		t.Errorf("%s: expected response to contain %q", tc.name, tc.message)
+	    }
+	}
+    })
+
+    board, err := Th.q.GetBoardByName(context.Background(), "music")
+    if err != nil {
+	t.Fatalf("expected no error, got %v", err)
+    }
+    id := strconv.Itoa(int(board.BoardID))
+
+    t.Run("rename", func(t *testing.T) {
+	w := httptest.NewRecorder()
+	Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"rename"}, "board_id": {id}, "name": {"music2"}}))
+
+	board, err := Th.q.GetBoard(context.Background(), board.BoardID)
+	if err != nil {
+	    t.Errorf("expected no error, got %v", err)
+	}
+	if board.Name != "music2" {
+	    t.Errorf("expected board to be renamed to music2, got %s", board.Name)
+	}
+    })
+
+    t.Run("limits", func(t *testing.T) {
+	w := httptest.NewRecorder()
+	Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"limits"}, "board_id": {id}, "max_threads": {"5"}, "max_replies": {"50"}, "bump_limit": {"40"}, "max_comment": {"300"}}))
+
+	board, err := Th.q.GetBoard(context.Background(), board.BoardID)
+	if err != nil {
+	    t.Errorf("expected no error, got %v", err)
+	}
+	if board.MaxThreads != 5 || board.MaxReplies != 50 || board.MaxComment != 300 {
+	    t.Errorf("expected limits to be 5, 50 and 300, got %d, %d and %d", board.MaxThreads, board.MaxReplies, board.MaxComment)
+	}
+
+	w = httptest.NewRecorder()
+	Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"limits"}, "board_id": {id}, "max_threads": {"5"}, "max_replies": {"50"}, "bump_limit": {"40"}, "max_comment": {"5000"}}))
+	if !strings.Contains(w.Body.String(), "Comments can have at most 1275 characters") {
+	    t.Errorf("expected comment limit above the column size to be rejected")
+	}
+    })
+
+    t.Run("links", func(t *testing.T) {
+	w := httptest.NewRecorder()
+	Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"links"}, "board_id": {id}, "allowed_domains": {"Go.dev github.com"}}))
+
+	board, err := Th.q.GetBoard(context.Background(), board.BoardID)
+	if err != nil {
+	    t.Errorf("expected no error, got %v", err)
+	}
+	if board.LinksEnabled || board.AllowedDomains != "go.dev, github.com" {
+	    t.Errorf("expected links to be disabled with go.dev and github.com allowed, got %v and %q", board.LinksEnabled, board.AllowedDomains)
+	}
+
+	w = httptest.NewRecorder()
+	Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"links"}, "board_id": {id}, "links_enabled": {"on"}, "denied_domains": {"not a/domain"}}))
+	if !strings.Contains(w.Body.String(), "is not a valid domain") {
+	    t.Errorf("expected an invalid domain to be rejected")
+	}
+    })
+
+    t.Run("files", func(t *testing.T) {
+	w := httptest.NewRecorder()
+	Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"files"}, "board_id": {id}, "max_file_size": {"4096"}, "allowed_types": {"image/png application/pdf"}, "duplicate_window": {"30"}}))
+
+	board, err := Th.q.GetBoard(context.Background(), board.BoardID)
+	if err != nil {
+	    t.Errorf("expected no error, got %v", err)
+	}
+	if board.MaxFileSize != 4096 || board.AllowedTypes != "image/png, application/pdf" || board.DuplicateWindow != 30 {
+	    t.Errorf("expected files up to 4096 bytes of png and pdf with a 30 minute window, got %d, %q and %d", board.MaxFileSize, board.AllowedTypes, board.DuplicateWindow)
+	}
+
+	w = httptest.NewRecorder()
+	Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"files"}, "board_id": {id}, "max_file_size": {"4096"}, "allowed_types": {"text/html"}, "duplicate_window": {"30"}}))
+	if !strings.Contains(w.Body.String(), "is not a supported file type") {
+	    t.Errorf("expected an unsupported type to be rejected")
+	}
+    })
+
+    t.Run("rates", func(t *testing.T) {
+	w := httptest.NewRecorder()
+	Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"rates"}, "board_id": {id}, "thread_burst": {"2"}, "thread_interval": {"600"}, "reply_burst": {"10"}, "reply_interval": {"0"}}))
+
+	board, err := Th.q.GetBoard(context.Background(), board.BoardID)
+	if err != nil {
+	    t.Errorf("expected no error, got %v", err)
+	}
+	if board.ThreadBurst != 2 || board.ThreadInterval != 600 || board.ReplyBurst != 10 || board.ReplyInterval != 0 {
+	    t.Errorf("expected the rate limits to be saved, got %d, %d, %d and %d", board.ThreadBurst, board.ThreadInterval, board.ReplyBurst, board.ReplyInterval)
+	}
+
+	w = httptest.NewRecorder()
+	Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"rates"}, "board_id": {id}, "thread_burst": {"0"}, "thread_interval": {"600"}, "reply_burst": {"10"}, "reply_interval": {"0"}}))
+	if !strings.Contains(w.Body.String(), "Thread burst must be a number between 1 and 100") {
+	    t.Errorf("expected a burst of 0 to be rejected")
+	}
+    })
+
+    t.Run("archive", func(t *testing.T) {
+	w := httptest.NewRecorder()
+	Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"archive"}, "board_id": {id}}))
+
+	w = httptest.NewRecorder()
+	Th.ServePost(w, httptest.NewRequest(http.MethodGet, "/post/music2", nil))
+	res := w.Result()
+	defer res.Body.Close()
+
+	url, err := res.Location()
+	if err != nil {
+	    t.Errorf("Expected no errors, got %v", err)
+	}
+	if url.Path != "/error/403" {
+	    t.Errorf("expected path to be /error/403 but got " + url.Path)
+	}
+
+	w = httptest.NewRecorder()
+	Th.ServeBoard(w, httptest.NewRequest(http.MethodGet, "/board/music2", nil))
+	if w.Result().StatusCode != http.StatusOK {
+	    t.Errorf("expected archived board to still be served, got %d", w.Result().StatusCode)
+	}
+    })
+
+    t.Run("delete without confirmation", func(t *testing.T) {
+	w := httptest.NewRecorder()
+	Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"delete"}, "board_id": {id}}))
+	if w.Header().Get("Location") != "/error/400" {
+	    t.Errorf("expected an unconfirmed delete to be refused, got %q", w.Header().Get("Location"))
+	}
+
+	if _, err := Th.q.GetBoard(context.Background(), board.BoardID); err != nil {
+	    t.Errorf("expected board to be kept, got %v", err)
+	}
+    })
+
+    t.Run("delete", func(t *testing.T) {
+	w := httptest.NewRecorder()
+	Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"delete"}, "board_id": {id}, "confirm": {"on"}}))
+
+	_, err := Th.q.GetBoard(context.Background(), board.BoardID)
+	if !errors.Is(err, sql.ErrNoRows) {
+	    t.Errorf("expected board to be deleted, got %v", err)
+	}
+    })
+}
+
+func TestServeAdminDeleteFiles(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+    Th.cfg.UploadDir = t.TempDir()
+
+    w := httptest.NewRecorder()
+    Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"create"}, "name": {"pictures"}}))
+    board, err := Th.q.GetBoardByName(context.Background(), "pictures")
+    if err != nil {
+	t.Fatalf("expected no error, got %v", err)
+    }
+
+    var buff bytes.Buffer
+    if err := png.Encode(&buff, image.NewNRGBA(image.Rect(0, 0, 20, 20))); err != nil {
+	t.Fatalf("expected no error, got %v", err)
+    }
+    fields := map[string]string{"title": "A thread with a file", "comment": "Look at this"}
+    Th.ServePost(httptest.NewRecorder(), multipartRequest("/post/pictures", fields, "cat.png", buff.Bytes()))
+
+    attachments, err := Th.q.GetAllBoardAttachments(context.Background(), board.BoardID)
+    if err != nil || len(attachments) != 1 {
+	t.Fatalf("expected 1 attachment, got %v", err)
+    }
+    names := uploadFiles(attachments[0].FileName, attachments[0].ThumbName)
+
+    w = httptest.NewRecorder()
+    Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"delete"}, "board_id": {strconv.Itoa(int(board.BoardID))}, "confirm": {"on"}}))
+
+    for _, name := range names {
+	if _, err := os.Stat(filepath.Join(Th.uploadDir(), name)); !os.IsNotExist(err) {
+	    t.Errorf("expected %q of the deleted board to be removed, got %v", name, err)
+	}
+    }
+}
@@ -2,6 +2,8 @@ package controllers
 
 import (
 	"time"
+	"errors"
+	"database/sql"
 	"strings"
 	"context"
 	"strconv"
@@ -24,15 +26,16 @@ func (h *Handler) ServeIndex(w http.ResponseWriter, r *http.Request) {
 	    }
 	}
 
-	threads, err := h.q.GetThreads(context.Background(), 3)
+	page, err := utils.GetPageNumber(r.URL.Query().Get("page"))
 	if err != nil {
-	    log.Fatal(err)
-	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusNotFound), http.StatusSeeOther)
 	    return
 	}
 
-	data := models.IndexData{
-	    Threads: threads,
+	data, err := utils.GetIndexData(h.q, page, h.recentCount())
+	if err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(utils.ErrorStatus(err)), http.StatusSeeOther)
+	    return
 	}
 
 	tmpl.ExecuteTemplate(w, "layout", data)
@@ -42,17 +45,36 @@ func (h *Handler) ServeIndex(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) ServeBoard(w http.ResponseWriter, r *http.Request) {
 	tmpl := utils.Serve("board")
 	
-	vs, err := utils.GetPathValues(strings.Split(r.URL.Path, "/"), utils.PathWant{BoardName: true, ThreadID: false, Status: false})
+	vs, err := utils.GetPathValues(strings.Split(r.URL.Path, "/"), utils.PathWant{BoardName: true, ThreadID: false, Status: false, Subpage: true})
 	if err != nil {
 	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusNotFound), http.StatusSeeOther)
 	    return
 	}
-	name := vs.BoardName
-	id := utils.GetBoardID(name)
+	board, err := h.q.GetBoardByName(context.Background(), vs.BoardName)
+	if err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(utils.ErrorStatus(err)), http.StatusSeeOther)
+	    return
+	}
 
-	data, err := utils.GetBoardData(h.q, id, name)
+	switch vs.Subpage {
+	case "":
+	case "catalog":
+	    h.serveCatalog(w, r, board)
+	    return
+	default:
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusNotFound), http.StatusSeeOther)
+	    return
+	}
+
+	page, err := utils.GetPageNumber(r.URL.Query().Get("page"))
 	if err != nil {
-	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusNotFound), http.StatusSeeOther)
+	    return
+	}
+
+	data, err := utils.GetBoardData(h.q, board, page, h.pageSize(), h.previews())
+	if err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(utils.ErrorStatus(err)), http.StatusSeeOther)
 	    return
 	}
 
@@ -60,6 +82,18 @@ func (h *Handler) ServeBoard(w http.ResponseWriter, r *http.Request) {
 }
 
 
+func (h *Handler) serveCatalog(w http.ResponseWriter, r *http.Request, board sqlc.Board) {
+	tmpl := utils.Serve("catalog")
+
+	data, err := utils.GetCatalogData(h.q, board, r.URL.Query().Get("sort"))
+	if err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
+	    return
+	}
+
+	tmpl.ExecuteTemplate(w, "layout", data)
+}
+
 func (h *Handler) ServeThread(w http.ResponseWriter, r *http.Request) {
 	tmpl := utils.Serve("thread")
 
@@ -72,9 +106,17 @@ func (h *Handler) ServeThread(w http.ResponseWriter, r *http.Request) {
 
 	data, err := utils.GetThreadData(h.q, int32(id))
 	if err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(utils.ErrorStatus(err)), http.StatusSeeOther)
+	    return
+	}
+
+	token, err := h.csrfToken(w, r)
+	if err != nil {
+	    log.Print(err)
 	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
 	    return
 	}
+	data.CSRFToken = token
 
 	tmpl.ExecuteTemplate(w, "layout", data)
 }
@@ -90,66 +132,143 @@ func (h *Handler) ServePost(w http.ResponseWriter, r *http.Request) {
 	}
 	name := vs.BoardName
 
+	board, err := h.q.GetBoardByName(context.Background(), name)
+	if err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(utils.ErrorStatus(err)), http.StatusSeeOther)
+	    return
+	}
+	id := board.BoardID
+
+	if board.Archived {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusForbidden), http.StatusSeeOther)
+	    return
+	}
+
+	boards, err := h.q.ListBoards(context.Background())
+	if err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
+	    return
+	}
+
+	token, err := h.csrfToken(w, r)
+	if err != nil {
+	    log.Print(err)
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
+	    return
+	}
+
 	switch method {
 	case "GET":
 	    data := models.PostData{
 		Title: "",
 		Comment: "",
 		Board: name,
+		MaxComment: board.MaxComment,
 		Errors: [2]models.FormError{
 		    {Bool: false, Message: "", Field: "title"},
 		    {Bool: false, Message: "", Field: "comment"},
 		},
+		FileError: models.FormError{Bool: false, Message: "", Field: "file"},
+		AllowedTypes: board.AllowedTypes,
+		ForceAnon: board.ForceAnon,
+		Boards: boards,
+		CSRFToken: token,
+	    }
+	    if board.MaxFileSize == 0 {
+		data.AllowedTypes = ""
 	    }
 	    tmpl.ExecuteTemplate(w, "layout", data)
 	    return
 
 	case "POST":
-	    id := utils.GetBoardID(name)
+	    if err := h.parseForm(w, r, board); err != nil {
+		http.Redirect(w, r, "/error/" + strconv.Itoa(formStatus(err)), http.StatusSeeOther)
+		return
+	    }
 
-	    if err := r.ParseForm(); err != nil {
+	    if !h.checkCSRF(r) {
+		http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusForbidden), http.StatusSeeOther)
+		return
+	    }
+
+	    file, fileError, fileErr := h.validateUpload(r, board)
+	    if fileErr != nil && !fileError.Bool {
+		log.Print(fileErr)
 		http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
 		return
 	    }
 
-	    errors, err := utils.ValidatePost(r.FormValue("title"), r.FormValue("comment"))
-	    if err != nil {
+	    errors, err := utils.ValidatePost(r.FormValue("title"), r.FormValue("comment"), board.MaxComment)
+	    if err != nil || fileErr != nil {
 		data := models.PostData{
 		    Title: r.FormValue("title"),
 		    Comment: r.FormValue("comment"),
 		    Board: name,
+		    MaxComment: board.MaxComment,
 		    Errors: errors,
+		    FileError: fileError,
+		    AllowedTypes: board.AllowedTypes,
+		    Name: r.FormValue("name"),
+		    ForceAnon: board.ForceAnon,
+		    Boards: boards,
+		    CSRFToken: token,
+		}
+		if board.MaxFileSize == 0 {
+		    data.AllowedTypes = ""
 		}
-		data.Errors = errors
 		tmpl.ExecuteTemplate(w, "layout", data)
 		return
 	    }
 
-	    nr, err := h.q.CountThreads(context.Background()); if err != nil {
-		http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusNotFound), http.StatusSeeOther)
+	    // only posts that would be created count against the limit, a
+	    // form sent back with errors costs nothing
+	    allowed, err := h.allowPost("thread", board, clientIP(r), board.ThreadBurst, board.ThreadInterval)
+	    if err != nil {
+		log.Print(err)
+		http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
+		return
+	    }
+	    if !allowed {
+		http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusTooManyRequests), http.StatusSeeOther)
 		return
 	    }
 
-	    if nr >= 20 {
-		oldestThread, err := h.q.GetOldestThread(context.Background(), id)
+	    posterName, tripcode := "", ""
+	    if !board.ForceAnon {
+		posterName, tripcode = utils.ParseName(r.FormValue("name"))
+	    }
+
+	    hash, err := utils.HashPassword(r.FormValue("password"))
+	    if err != nil {
+		log.Print(err)
+		http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
+		return
+	    }
+
+	    var attachment *sqlc.CreateAttachmentParams
+	    if file != nil {
+		params, err := h.saveUpload(file)
 		if err != nil {
+		    log.Print(err)
 		    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
 		    return
 		}
-		_, nerr := h.q.DeleteThread(context.Background(), int32(oldestThread.ThreadID))
-		if nerr != nil{
-		    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
-		    return
-		}
+		attachment = &params
 	    }
 
-	    if _, err := h.q.CreateThread(context.Background(), sqlc.CreateThreadParams{
+	    now := time.Now()
+	    if err := h.createThread(sqlc.CreateThreadParams{
 		Title: r.FormValue("title"),
 		Comment: r.FormValue("comment"),
-		Date: strconv.Itoa(int(time.Now().Unix())),
+		Date: now,
+		LastBumpedAt: now,
 		BoardID: id,
-	    }); err != nil {
-		http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusNotFound), http.StatusSeeOther)
+		DeleteHash: hash,
+		Name: posterName,
+		Tripcode: tripcode,
+	    }, attachment, clientIP(r)); err != nil {
+		log.Print(err)
+		http.Redirect(w, r, "/error/" + strconv.Itoa(utils.ErrorStatus(err)), http.StatusSeeOther)
 		return
 	    }
 
@@ -159,6 +278,88 @@ func (h *Handler) ServePost(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// createThread prunes the board and creates the new thread in one
+// transaction. The board row is locked first, so concurrent posts to the
+// same board take turns and can never push it over its limit. The files of
+// pruned threads are removed once the transaction is committed, and the
+// already stored upload of the new thread if it is rolled back.
+func (h *Handler) createThread(params sqlc.CreateThreadParams, attachment *sqlc.CreateAttachmentParams, ip string) error {
+	retired := []string{}
+
+	err := h.withTx(context.Background(), func(q *sqlc.Queries) error {
+	    board, err := q.GetBoardForUpdate(context.Background(), params.BoardID)
+	    if err != nil {
+		return err
+	    }
+
+	    if board.Archived {
+		return &models.StatusError{Status: http.StatusForbidden, Message: "Board is archived"}
+	    }
+
+	    nr, err := q.CountBoardThreads(context.Background(), params.BoardID)
+	    if err != nil {
+		return err
+	    }
+
+	    // a lowered limit can leave the board with more than one thread
+	    // too many, so prune until there is room for the new one
+	    for ; nr >= int64(board.MaxThreads); nr-- {
+		oldestThread, err := q.GetOldestThread(context.Background(), params.BoardID)
+		if err != nil {
+		    return err
+		}
+
+		names, err := retireThread(q, oldestThread.ThreadID)
+		if err != nil {
+		    return err
+		}
+		retired = append(retired, names...)
+	    }
+
+	    res, err := q.CreateThread(context.Background(), params)
+	    if err != nil {
+		return err
+	    }
+
+	    threadID, err := res.LastInsertId()
+	    if err != nil {
+		return err
+	    }
+
+	    // the poster ID depends on the thread, so it can only be set
+	    // once the thread has one
+	    if board.PosterIds {
+		if _, err := q.SetThreadPosterID(context.Background(), sqlc.SetThreadPosterIDParams{
+		    PosterID: utils.PosterID(h.cfg.Secret, ip, int32(threadID)),
+		    ThreadID: int32(threadID),
+		}); err != nil {
+		    return err
+		}
+	    }
+
+	    if err := createQuotes(q, int32(threadID), sql.NullInt32{}, params.Comment); err != nil {
+		return err
+	    }
+
+	    if attachment == nil {
+		return nil
+	    }
+
+	    attachment.ThreadID = int32(threadID)
+	    return createAttachment(q, board, *attachment)
+	})
+
+	if err != nil {
+	    if attachment != nil {
+		h.removeFiles(uploadFiles(attachment.FileName, attachment.ThumbName))
+	    }
+	    return err
+	}
+
+	h.removeFiles(retired)
+	return nil
+}
+
 func (h *Handler) ServeReply(w http.ResponseWriter, r *http.Request) {
 	tmpl := utils.Serve("reply")
 	method := r.Method
@@ -170,56 +371,159 @@ func (h *Handler) ServeReply(w http.ResponseWriter, r *http.Request) {
 	}
 	id := vs.ThreadID
 
+	thread, err := h.q.GetThread(context.Background(), int32(id))
+	if err != nil {
+	    status := utils.ErrorStatus(err)
+	    // the reply form was open on a thread that has been killed since
+	    if method == "POST" && status == http.StatusNotFound {
+		status = http.StatusGone
+	    }
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(status), http.StatusSeeOther)
+	    return
+	}
+
+	board, err := h.q.GetBoard(context.Background(), thread.BoardID)
+	if err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
+	    return
+	}
+
+	if board.Archived {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusForbidden), http.StatusSeeOther)
+	    return
+	}
+
+	if thread.Status != sqlc.ThreadsStatusAlive {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusGone), http.StatusSeeOther)
+	    return
+	}
+
+	boards, err := h.q.ListBoards(context.Background())
+	if err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
+	    return
+	}
+
+	token, err := h.csrfToken(w, r)
+	if err != nil {
+	    log.Print(err)
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
+	    return
+	}
+
 	switch method {
 	case "GET":
 	    data := models.ReplyData{
 		Comment: "",
 		Thread_id: id,
+		MaxComment: board.MaxComment,
 		Error: models.FormError{
 		    Bool: false, 
 		    Message: "", 
 		    Field: "",
 		},
+		FileError: models.FormError{Bool: false, Message: "", Field: "file"},
+		AllowedTypes: board.AllowedTypes,
+		ForceAnon: board.ForceAnon,
+		Boards: boards,
+		CSRFToken: token,
+	    }
+	    if board.MaxFileSize == 0 {
+		data.AllowedTypes = ""
 	    }
 	    tmpl.ExecuteTemplate(w, "layout", data)
 	    return
 	case "POST":
-	    if err := r.ParseForm(); err != nil {
+	    if err := h.parseForm(w, r, board); err != nil {
+		http.Redirect(w, r, "/error/" + strconv.Itoa(formStatus(err)), http.StatusSeeOther)
+		return
+	    }
+
+	    if !h.checkCSRF(r) {
+		http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusForbidden), http.StatusSeeOther)
+		return
+	    }
+
+	    file, fileError, fileErr := h.validateUpload(r, board)
+	    if fileErr != nil && !fileError.Bool {
+		log.Print(fileErr)
 		http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
 		return
 	    }
 
-	    error, err := utils.ValidateReply(r.FormValue("comment"))
-	    if err != nil {
+	    error, err := utils.ValidateReply(r.FormValue("comment"), board.MaxComment)
+	    if err != nil || fileErr != nil {
 		data := models.ReplyData{
 		    Comment: r.FormValue("comment"),
 		    Thread_id: id,
+		    MaxComment: board.MaxComment,
+		    Sage: r.FormValue("sage") != "",
 		    Error: error,
+		    FileError: fileError,
+		    AllowedTypes: board.AllowedTypes,
+		    Name: r.FormValue("name"),
+		    ForceAnon: board.ForceAnon,
+		    Boards: boards,
+		    CSRFToken: token,
+		}
+		if board.MaxFileSize == 0 {
+		    data.AllowedTypes = ""
 		}
 		tmpl.ExecuteTemplate(w, "layout", data)
 		return
 	    }
 
-	    nr, err := h.q.CountReplies(context.Background(), int32(id)); if err != nil {
-		http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusNotFound), http.StatusSeeOther)
+	    allowed, err := h.allowPost("reply", board, clientIP(r), board.ReplyBurst, board.ReplyInterval)
+	    if err != nil {
+		log.Print(err)
+		http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
+		return
+	    }
+	    if !allowed {
+		http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusTooManyRequests), http.StatusSeeOther)
+		return
+	    }
+
+	    posterName, tripcode := "", ""
+	    if !board.ForceAnon {
+		posterName, tripcode = utils.ParseName(r.FormValue("name"))
+	    }
+
+	    hash, err := utils.HashPassword(r.FormValue("password"))
+	    if err != nil {
+		log.Print(err)
+		http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
 		return
 	    }
 
-	    if nr >= 20 {
-		_, err := h.q.DeleteThread(context.Background(), int32(id)); if err != nil{
+	    var attachment *sqlc.CreateAttachmentParams
+	    if file != nil {
+		params, err := h.saveUpload(file)
+		if err != nil {
+		    log.Print(err)
 		    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
 		    return
 		}
-		http.Redirect(w, r, "/kill/" + strconv.Itoa(id), http.StatusSeeOther)
-		return
+		attachment = &params
 	    }
 
-	    if _, err := h.q.CreateReply(context.Background(), sqlc.CreateReplyParams{
+	    killed, err := h.createReply(sqlc.CreateReplyParams{
 		Comment: r.FormValue("comment"),
-		Date: strconv.Itoa(int(time.Now().Unix())),
+		Date: time.Now(),
+		Sage: r.FormValue("sage") != "",
 		ThreadID: int32(id),
-	    }); err != nil {
-		http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
+		DeleteHash: hash,
+		Name: posterName,
+		Tripcode: tripcode,
+	    }, attachment, clientIP(r))
+	    if err != nil {
+		log.Print(err)
+		http.Redirect(w, r, "/error/" + strconv.Itoa(utils.ErrorStatus(err)), http.StatusSeeOther)
+		return
+	    }
+
+	    if killed {
+		http.Redirect(w, r, "/kill/" + strconv.Itoa(id), http.StatusSeeOther)
 		return
 	    }
 
@@ -228,6 +532,293 @@ func (h *Handler) ServeReply(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// createReply stores the reply, or kills the thread if it has reached its
+// limit, in one transaction. The thread row is locked first, so a reply
+// that loses the race against the request killing the thread finds it gone
+// instead of failing on the foreign key. Replies below the board's bump
+// limit also move the thread back to the top of the board, unless they are
+// saged. The upload of the reply is removed if it is not stored, and the
+// files of a killed thread once it is.
+func (h *Handler) createReply(params sqlc.CreateReplyParams, attachment *sqlc.CreateAttachmentParams, ip string) (bool, error) {
+	killed := false
+	retired := []string{}
+
+	err := h.withTx(context.Background(), func(q *sqlc.Queries) error {
+	    thread, err := q.GetThreadForUpdate(context.Background(), params.ThreadID)
+	    if errors.Is(err, sql.ErrNoRows) {
+		return &models.StatusError{Status: http.StatusGone, Message: "Thread already died"}
+	    }
+	    if err != nil {
+		return err
+	    }
+	    if thread.Status != sqlc.ThreadsStatusAlive {
+		return &models.StatusError{Status: http.StatusGone, Message: "Thread already died"}
+	    }
+
+	    board, err := q.GetBoard(context.Background(), thread.BoardID)
+	    if err != nil {
+		return err
+	    }
+
+	    if board.Archived {
+		return &models.StatusError{Status: http.StatusForbidden, Message: "Board is archived"}
+	    }
+
+	    nr, err := q.CountReplies(context.Background(), thread.ThreadID)
+	    if err != nil {
+		return err
+	    }
+
+	    if nr >= int64(board.MaxReplies) {
+		names, err := retireThread(q, thread.ThreadID)
+		if err != nil {
+		    return err
+		}
+		retired = names
+		killed = true
+		return nil
+	    }
+
+	    if board.PosterIds {
+		params.PosterID = utils.PosterID(h.cfg.Secret, ip, thread.ThreadID)
+	    }
+
+	    res, err := q.CreateReply(context.Background(), params)
+	    if err != nil {
+		return err
+	    }
+
+	    replyID, err := res.LastInsertId()
+	    if err != nil {
+		return err
+	    }
+
+	    if err := createQuotes(q, thread.ThreadID, sql.NullInt32{Int32: int32(replyID), Valid: true}, params.Comment); err != nil {
+		return err
+	    }
+
+	    if attachment != nil {
+		attachment.ThreadID = thread.ThreadID
+		attachment.ReplyID = sql.NullInt32{Int32: int32(replyID), Valid: true}
+		if err := createAttachment(q, board, *attachment); err != nil {
+		    return err
+		}
+	    }
+
+	    // sage replies and replies past the bump limit are still stored
+	    // but the thread keeps its place on the board
+	    if params.Sage || nr >= int64(board.BumpLimit) {
+		return nil
+	    }
+
+	    _, err = q.BumpThread(context.Background(), sqlc.BumpThreadParams{
+		LastBumpedAt: params.Date,
+		ThreadID: thread.ThreadID,
+	    })
+	    return err
+	})
+
+	// a killed thread takes no reply, so the upload has nowhere to go
+	if attachment != nil && (err != nil || killed) {
+	    h.removeFiles(uploadFiles(attachment.FileName, attachment.ThumbName))
+	}
+	if err == nil {
+	    h.removeFiles(retired)
+	}
+
+	return killed, err
+}
+
+// createQuotes stores the posts quoted by a post of a thread so they can
+// link back to it, an opening post has no reply ID. Quotes of posts that do
+// not exist, or of threads on another board than the one named, are left
+// out. Thread quotes keep the board they name, so a comment naming the
+// same thread ID on two boards only links the right one.
+func createQuotes(q *sqlc.Queries, threadID int32, replyID sql.NullInt32, comment string) error {
+	for _, ref := range utils.ParseQuotes(comment) {
+	    params := sqlc.CreateQuoteParams{ThreadID: threadID, ReplyID: replyID}
+
+	    if ref.ReplyID != 0 {
+		reply, err := q.GetReply(context.Background(), ref.ReplyID)
+		if errors.Is(err, sql.ErrNoRows) {
+		    continue
+		}
+		if err != nil {
+		    return err
+		}
+		params.QuotedThreadID = reply.ThreadID
+		params.QuotedReplyID = sql.NullInt32{Int32: reply.ReplyID, Valid: true}
+	    } else {
+		thread, err := q.GetThread(context.Background(), ref.ThreadID)
+		if errors.Is(err, sql.ErrNoRows) {
+		    continue
+		}
+		if err != nil {
+		    return err
+		}
+		board, err := q.GetBoard(context.Background(), thread.BoardID)
+		if err != nil {
+		    return err
+		}
+		if board.Name != ref.Board {
+		    continue
+		}
+		params.QuotedThreadID = thread.ThreadID
+		params.QuotedBoard = ref.Board
+	    }
+
+	    if _, err := q.CreateQuote(context.Background(), params); err != nil {
+		return err
+	    }
+	}
+
+	return nil
+}
+
+// ServeDelete lets the author of a post delete it with the password they
+// gave when posting. The reply_id field picks the reply to delete, without
+// it the opening post is deleted and the thread is archived.
+func (h *Handler) ServeDelete(w http.ResponseWriter, r *http.Request) {
+	vs, err := utils.GetPathValues(strings.Split(r.URL.Path, "/"), utils.PathWant{BoardName: false, ThreadID: true, Status: false})
+	if err != nil || r.Method != "POST" {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusNotFound), http.StatusSeeOther)
+	    return
+	}
+	id := vs.ThreadID
+
+	if err := r.ParseForm(); err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusBadRequest), http.StatusSeeOther)
+	    return
+	}
+
+	if !h.checkCSRF(r) {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusForbidden), http.StatusSeeOther)
+	    return
+	}
+
+	var replyID int
+	if value := r.FormValue("reply_id"); value != "" {
+	    replyID, err = strconv.Atoi(value)
+	    if err != nil || replyID < 1 {
+		http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusNotFound), http.StatusSeeOther)
+		return
+	    }
+	}
+
+	board, err := h.deletePost(int32(id), int32(replyID), r.FormValue("password"))
+	if err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(utils.ErrorStatus(err)), http.StatusSeeOther)
+	    return
+	}
+
+	if replyID == 0 {
+	    http.Redirect(w, r, "/board/" + board.Name, http.StatusSeeOther)
+	    return
+	}
+
+	http.Redirect(w, r, "/thread/" + strconv.Itoa(id), http.StatusSeeOther)
+}
+
+// deletePost checks the delete password of a post and deletes it in one
+// transaction, with the thread row locked like a reply would. A deleted
+// opening post takes its thread off the board through the same path as a
+// pruned thread, it is archived so the replies of other posters and the
+// quotes pointing into it still work. A deleted reply keeps its row, so its
+// number and the quotes of it stay, and only loses its comment and files.
+func (h *Handler) deletePost(threadID int32, replyID int32, password string) (sqlc.Board, error) {
+	var board sqlc.Board
+	retired := []string{}
+
+	err := h.withTx(context.Background(), func(q *sqlc.Queries) error {
+	    thread, err := q.GetThreadForUpdate(context.Background(), threadID)
+	    if err != nil {
+		return err
+	    }
+	    if thread.Status != sqlc.ThreadsStatusAlive {
+		return &models.StatusError{Status: http.StatusGone, Message: "Thread already died"}
+	    }
+
+	    board, err = q.GetBoard(context.Background(), thread.BoardID)
+	    if err != nil {
+		return err
+	    }
+
+	    if board.Archived {
+		return &models.StatusError{Status: http.StatusForbidden, Message: "Board is archived"}
+	    }
+
+	    if replyID == 0 {
+		if !utils.CheckPassword(thread.DeleteHash, password) {
+		    return &models.StatusError{Status: http.StatusForbidden, Message: "Wrong delete password"}
+		}
+
+		names, err := retireThread(q, threadID)
+		if err != nil {
+		    return err
+		}
+		retired = names
+		return nil
+	    }
+
+	    reply, err := q.GetReply(context.Background(), replyID)
+	    if err != nil {
+		return err
+	    }
+	    if reply.ThreadID != threadID || reply.Deleted {
+		return &models.PathError{Message: "Not found"}
+	    }
+
+	    if !utils.CheckPassword(reply.DeleteHash, password) {
+		return &models.StatusError{Status: http.StatusForbidden, Message: "Wrong delete password"}
+	    }
+
+	    names, err := dropReplyAttachments(q, replyID)
+	    if err != nil {
+		return err
+	    }
+	    retired = names
+
+	    _, err = q.DeleteReply(context.Background(), replyID)
+	    return err
+	})
+
+	if err == nil {
+	    h.removeFiles(retired)
+	}
+
+	return board, err
+}
+
+func (h *Handler) ServeArchive(w http.ResponseWriter, r *http.Request) {
+	tmpl := utils.Serve("archive")
+
+	vs, err := utils.GetPathValues(strings.Split(r.URL.Path, "/"), utils.PathWant{BoardName: true, ThreadID: false, Status: false})
+	if err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusNotFound), http.StatusSeeOther)
+	    return
+	}
+
+	board, err := h.q.GetBoardByName(context.Background(), vs.BoardName)
+	if err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(utils.ErrorStatus(err)), http.StatusSeeOther)
+	    return
+	}
+
+	page, err := utils.GetPageNumber(r.URL.Query().Get("page"))
+	if err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusNotFound), http.StatusSeeOther)
+	    return
+	}
+
+	data, err := utils.GetArchiveData(h.q, board, page, h.pageSize())
+	if err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(utils.ErrorStatus(err)), http.StatusSeeOther)
+	    return
+	}
+
+	tmpl.ExecuteTemplate(w, "layout", data)
+}
+
 func (h *Handler) ServeKill(w http.ResponseWriter, r *http.Request) {
     tmpl := utils.Serve("kill")
 
@@ -238,8 +829,15 @@ func (h *Handler) ServeKill(w http.ResponseWriter, r *http.Request) {
     }
     id := vs.ThreadID
 
+    boards, err := h.q.ListBoards(context.Background())
+    if err != nil {
+	http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
+	return
+    }
+
     data := models.KillData{
 	Thread_id: id,
+	Boards: boards,
     }
 
     tmpl.ExecuteTemplate(w, "layout", data)
@@ -257,6 +855,12 @@ func (h *Handler) ServeError(w http.ResponseWriter, r *http.Request) {
 
     data := utils.CreateErrorData(status)
 
+    // the error page has nowhere to redirect to, so a failed board
+    // lookup just leaves the navigation empty
+    if boards, err := h.q.ListBoards(context.Background()); err == nil {
+	data.Boards = boards
+    }
+
     tmpl.ExecuteTemplate(w, "layout", data)
 }
 
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"testing"
+	"sync"
 	"bytes"
 	"strings"
 	"io"
@@ -13,6 +14,11 @@ import (
 	"html/template"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"mime/multipart"
+	"image"
+	"image/png"
+	"path/filepath"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/enzdor/gomsg/models"
@@ -23,6 +29,16 @@ import (
 
 var Th *Handler
 
+// The admin account of the test handler, the admin area is off unless the
+// config sets one.
+const (
+    testAdminUser = "admin"
+    testAdminPass = "admin password for the tests"
+)
+
+// testToken is the CSRF token of the visitor every test request comes from.
+var testToken string
+
 func stringTemplate(tmpl *template.Template, data any) (string, error){
     var buff bytes.Buffer 
     if err := tmpl.ExecuteTemplate(&buff, "layout", data); err != nil {
@@ -48,8 +64,32 @@ func start() error{
     if _, err := db.Query("DELETE FROM threads; "); err != nil {
 	return err
     }
+    if _, err := db.Query("DELETE FROM banned_hashes; "); err != nil {
+	return err
+    }
+    if _, err := db.Query("DELETE FROM rate_limits; "); err != nil {
+	return err
+    }
+    if _, err := db.Query("DELETE FROM upload_hashes; "); err != nil {
+	return err
+    }
+    if _, err := db.Query("DELETE FROM boards WHERE board_id > 3; "); err != nil {
+	return err
+    }
+    if _, err := db.Query("UPDATE boards SET archived = FALSE, max_threads = 20, max_replies = 20, bump_limit = 15, max_comment = 1200, links_enabled = TRUE, allowed_domains = '', denied_domains = '', max_file_size = 2097152, allowed_types = 'image/jpeg, image/png, image/gif, image/webp', duplicate_window = 1440, force_anon = FALSE, poster_ids = FALSE, thread_burst = 3, thread_interval = 0, reply_burst = 5, reply_interval = 0; "); err != nil {
+	return err
+    }
+
+    Th = NewHandler(db, Config{
+	AdminUser: testAdminUser,
+	AdminPass: testAdminPass,
+    })
 
-    Th = NewHandler(db)
+    token, err := utils.NewCSRFToken(Th.cfg.Secret)
+    if err != nil {
+	return err
+    }
+    testToken = token
 
     return nil
 }
@@ -96,19 +136,22 @@ func TestServeIndex(t *testing.T){
 	{
 	    Title: "This is the first title",
 	    Comment: "This is the first comment",
-	    Date: strconv.Itoa(int(time.Now().Unix())),
+	    Date: time.Now(),
+	    LastBumpedAt: time.Now(),
 	    BoardID: 1,
 	},
 	{
 	    Title: "This is the second title",
 	    Comment: "This is the second comment",
-	    Date: strconv.Itoa(int(time.Now().Unix())),
+	    Date: time.Now(),
+	    LastBumpedAt: time.Now(),
 	    BoardID: 1,
 	},
 	{
 	    Title: "This is the third title",
 	    Comment: "This is the third comment",
-	    Date: strconv.Itoa(int(time.Now().Unix())),
+	    Date: time.Now(),
+	    LastBumpedAt: time.Now(),
 	    BoardID: 3,
 	},
     }
@@ -122,11 +165,15 @@ func TestServeIndex(t *testing.T){
 	}
     }
 
-    threads, err := Th.q.GetThreads(context.Background(), 3)
+    data, err := utils.GetIndexData(Th.q, 1, DefaultRecentCount)
     if err != nil {
 	t.Errorf("expected no error, got %v", err)
     }
 
+    if len(data.Posts) != len(createThreads) {
+	t.Errorf("expected %d recent posts, got %d", len(createThreads), len(data.Posts))
+    }
+
     testCases := []struct {
 	name	string
 	req 	*http.Request
@@ -139,9 +186,7 @@ func TestServeIndex(t *testing.T){
 	    req: httptest.NewRequest(http.MethodGet, "/", nil),
 	    w: httptest.NewRecorder(), 
 	    te: utils.Serve("index"),
-	    te_data: models.IndexData{
-		Threads: threads,
-	    },
+	    te_data: data,
 	},
     } 
 
@@ -185,55 +230,64 @@ func TestServeBoard(t *testing.T) {
 	{
 	    Title: "This is the first title",
 	    Comment: "This is the first comment",
-	    Date: strconv.Itoa(int(time.Now().Unix())),
+	    Date: time.Now(),
+	    LastBumpedAt: time.Now(),
 	    BoardID: 1,
 	},
 	{
 	    Title: "This is the second title",
 	    Comment: "This is the second comment",
-	    Date: strconv.Itoa(int(time.Now().Unix())),
+	    Date: time.Now(),
+	    LastBumpedAt: time.Now(),
 	    BoardID: 1,
 	},
 	{
 	    Title: "This is the third title",
 	    Comment: "This is the third comment",
-	    Date: strconv.Itoa(int(time.Now().Unix())),
+	    Date: time.Now(),
+	    LastBumpedAt: time.Now(),
 	    BoardID: 1,
 	},
 	{
 	    Title: "This is the first title",
 	    Comment: "This is the first comment",
-	    Date: strconv.Itoa(int(time.Now().Unix())),
+	    Date: time.Now(),
+	    LastBumpedAt: time.Now(),
 	    BoardID: 2,
 	},
 	{
 	    Title: "This is the second title",
 	    Comment: "This is the second comment",
-	    Date: strconv.Itoa(int(time.Now().Unix())),
+	    Date: time.Now(),
+	    LastBumpedAt: time.Now(),
 	    BoardID: 2,
 	},
 	{
 	    Title: "This is the third title",
 	    Comment: "This is the third comment",
-	    Date: strconv.Itoa(int(time.Now().Unix())),
+	    Date: time.Now(),
+	    LastBumpedAt: time.Now(),
 	    BoardID: 2,
 	},
 	{
 	    Title: "This is the first title",
 	    Comment: "This is the first comment",
-	    Date: strconv.Itoa(int(time.Now().Unix())),
+	    Date: time.Now(),
+	    LastBumpedAt: time.Now(),
 	    BoardID: 3,
 	},
 	{
 	    Title: "This is the second title",
 	    Comment: "This is the second comment",
-	    Date: strconv.Itoa(int(time.Now().Unix())),
+	    Date: time.Now(),
+	    LastBumpedAt: time.Now(),
 	    BoardID: 3,
 	},
 	{
 	    Title: "This is the third title",
 	    Comment: "This is the third comment",
-	    Date: strconv.Itoa(int(time.Now().Unix())),
+	    Date: time.Now(),
+	    LastBumpedAt: time.Now(),
 	    BoardID: 3,
 	},
     }
@@ -275,8 +329,12 @@ func TestServeBoard(t *testing.T) {
 
     for _, tc := range testCases {
 	t.Run(tc.name, func(t *testing.T){
-	    id := utils.GetBoardID(tc.name)
-	    data, err := utils.GetBoardData(Th.q, id, tc.name)
+	    board, err := Th.q.GetBoardByName(context.Background(), tc.name)
+	    if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	    }
+
+	    data, err := utils.GetBoardData(Th.q, board, 1, DefaultPageSize, DefaultPreviews)
 	    if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	    }
@@ -320,19 +378,22 @@ func TestServeThread(t *testing.T) {
 	{
 	    Title: "This is the first title",
 	    Comment: "This is the first comment",
-	    Date: strconv.Itoa(int(time.Now().Unix())),
+	    Date: time.Now(),
+	    LastBumpedAt: time.Now(),
 	    BoardID: 1,
 	},
 	{
 	    Title: "This is the second title",
 	    Comment: "This is the second comment",
-	    Date: strconv.Itoa(int(time.Now().Unix())),
+	    Date: time.Now(),
+	    LastBumpedAt: time.Now(),
 	    BoardID: 2,
 	},
 	{
 	    Title: "This is the third title",
 	    Comment: "This is the third comment",
-	    Date: strconv.Itoa(int(time.Now().Unix())),
+	    Date: time.Now(),
+	    LastBumpedAt: time.Now(),
 	    BoardID: 3,
 	},
     }
@@ -340,17 +401,17 @@ func TestServeThread(t *testing.T) {
     createReplies := []sqlc.CreateReplyParams{
 	{
 	    Comment: "This is the first comment",
-	    Date: strconv.Itoa(int(time.Now().Unix())),
+	    Date: time.Now(),
 	    ThreadID: 1,
 	},
 	{
 	    Comment: "This is the second comment",
-	    Date: strconv.Itoa(int(time.Now().Unix())),
+	    Date: time.Now(),
 	    ThreadID: 2,
 	},
 	{
 	    Comment: "This is the third comment",
-	    Date: strconv.Itoa(int(time.Now().Unix())),
+	    Date: time.Now(),
 	    ThreadID: 3,
 	},
     }
@@ -364,7 +425,7 @@ func TestServeThread(t *testing.T) {
 	}
     }
 
-    threads, err := Th.q.GetThreads(context.Background(), 3)
+    threads, err := Th.q.GetRecentThreads(context.Background(), 3)
     if err != nil {
 	t.Errorf("expected no error, got %v", err)
     }
@@ -446,6 +507,11 @@ func TestServeKill(t *testing.T) {
 	t.Errorf("expected no error, got %v", err)
     }
 
+    boards, err := Th.q.ListBoards(context.Background())
+    if err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
     testCases := []struct{
 	name 	string
 	id	int
@@ -464,6 +530,7 @@ func TestServeKill(t *testing.T) {
 	t.Run(tc.name, func(t *testing.T){
 	    data := models.KillData{
 		Thread_id: tc.id,
+		Boards: boards,
 	    }
 
 	    req := httptest.NewRequest(http.MethodGet, "/kill/" + strconv.Itoa(tc.id), nil)
@@ -494,6 +561,11 @@ func TestServeError(t *testing.T) {
 	t.Errorf("expected no error, got %v", err)
     }
 
+    boards, err := Th.q.ListBoards(context.Background())
+    if err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
     testCases := []struct{
 	name 	string
 	status	int
@@ -523,6 +595,7 @@ func TestServeError(t *testing.T) {
     for _, tc := range testCases {
 	t.Run(tc.name, func(t *testing.T){
 	    data := utils.CreateErrorData(tc.status)
+	    data.Boards = boards
 
 	    req := httptest.NewRequest(http.MethodGet, "/error/" + strconv.Itoa(tc.status), nil)
 
@@ -553,6 +626,11 @@ func TestServePost(t *testing.T) {
 	t.Errorf("expected no error, got %v", err)
     }
 
+    boards, err := Th.q.ListBoards(context.Background())
+    if err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
     getTestCases := []struct{
 	name 	string
 	board	string
@@ -569,10 +647,14 @@ func TestServePost(t *testing.T) {
 		Title: "",
 		Comment: "",
 		Board: "tech",
+		MaxComment: 1200,
 		Errors: [2]models.FormError{
 		    {Bool: false, Message: "", Field: "title"},
 		    {Bool: false, Message: "", Field: "comment"},
 		},
+		AllowedTypes: "image/jpeg, image/png, image/gif, image/webp",
+		CSRFToken: testToken,
+		Boards: boards,
 	    },
 	},
     }
@@ -586,7 +668,7 @@ func TestServePost(t *testing.T) {
 		t.Errorf("Expected no errors, got %v", err)
 	    }
 
-	    Th.ServePost(tc.w, req)
+	    Th.ServePost(tc.w, csrfRequest(req))
 	    res := tc.w.Result()
 	    defer res.Body.Close()
 
@@ -622,10 +704,14 @@ func TestServePost(t *testing.T) {
 		Title: "",
 		Comment: "",
 		Board: "tech",
+		MaxComment: 1200,
 		Errors: [2]models.FormError{
 		    {Bool: false, Message: "", Field: "title"},
 		    {Bool: false, Message: "", Field: "comment"},
 		},
+		AllowedTypes: "image/jpeg, image/png, image/gif, image/webp",
+		CSRFToken: testToken,
+		Boards: boards,
 	    },
 	},
 	{
@@ -639,10 +725,14 @@ func TestServePost(t *testing.T) {
 		Title: "a new post with a very interesting title",
 		Comment: "",
 		Board: "tech",
+		MaxComment: 1200,
 		Errors: [2]models.FormError{
 		    {Bool: false, Message: "", Field: "title"},
 		    {Bool: true, Message: "This field is required", Field: "comment"},
 		},
+		AllowedTypes: "image/jpeg, image/png, image/gif, image/webp",
+		CSRFToken: testToken,
+		Boards: boards,
 	    },
 	},
     }
@@ -652,7 +742,7 @@ func TestServePost(t *testing.T) {
 	    req := httptest.NewRequest(http.MethodPost, "/post/" + tc.board, tc.body)
 	    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	    Th.ServePost(tc.w, req)
+	    Th.ServePost(tc.w, csrfRequest(req))
 	    res := tc.w.Result()
 	    defer res.Body.Close()
 
@@ -690,7 +780,8 @@ func TestServeReply(t *testing.T) {
     threadParams := sqlc.CreateThreadParams{
 	Title: "This is the first title",
 	Comment: "This is the first comment",
-	Date: strconv.Itoa(int(time.Now().Unix())),
+	Date: time.Now(),
+	LastBumpedAt: time.Now(),
 	BoardID: 1,
     }
 
@@ -699,13 +790,18 @@ func TestServeReply(t *testing.T) {
 	    t.Errorf("Expected no errors, got %v", err)
     }
 
-    threads, err := Th.q.GetThreads(context.Background(), 1)
+    threads, err := Th.q.GetRecentThreads(context.Background(), 1)
     if err != nil {
 	    t.Errorf("Expected no errors, got %v", err)
     }
 
     thread := threads[0]
 
+    boards, err := Th.q.ListBoards(context.Background())
+    if err != nil {
+	    t.Errorf("Expected no errors, got %v", err)
+    }
+
     getTestCases := []struct{
 	name 	string
 	id	int
@@ -721,11 +817,15 @@ func TestServeReply(t *testing.T) {
 	    data: models.ReplyData{
 		Comment: "",
 		Thread_id: int(thread.ThreadID),
+		MaxComment: 1200,
 		Error: models.FormError{
 		    Bool: false, 
 		    Message: "", 
 		    Field: "comment",
 		},
+		AllowedTypes: "image/jpeg, image/png, image/gif, image/webp",
+		CSRFToken: testToken,
+		Boards: boards,
 	    },
 	},
     }
@@ -739,7 +839,7 @@ func TestServeReply(t *testing.T) {
 		t.Errorf("Expected no errors, got %v", err)
 	    }
 
-	    Th.ServeReply(tc.w, req)
+	    Th.ServeReply(tc.w, csrfRequest(req))
 	    res := tc.w.Result()
 	    defer res.Body.Close()
 
@@ -773,7 +873,11 @@ func TestServeReply(t *testing.T) {
 	    data: models.ReplyData{
 		Comment: "",
 		Thread_id: int(thread.ThreadID),
+		MaxComment: 1200,
 		Error: models.FormError{Bool: false, Message: "", Field: "comment"},
+		AllowedTypes: "image/jpeg, image/png, image/gif, image/webp",
+		CSRFToken: testToken,
+		Boards: boards,
 	    },
 	},
 	{
@@ -786,7 +890,11 @@ func TestServeReply(t *testing.T) {
 	    data: models.ReplyData{
 		Comment: "",
 		Thread_id: int(thread.ThreadID),
+		MaxComment: 1200,
 		Error: models.FormError{Bool: true, Message: "This field is required", Field: "comment"},
+		AllowedTypes: "image/jpeg, image/png, image/gif, image/webp",
+		CSRFToken: testToken,
+		Boards: boards,
 	    },
 	},
     }
@@ -796,7 +904,7 @@ func TestServeReply(t *testing.T) {
 	    req := httptest.NewRequest(http.MethodPost, "/reply/" + strconv.Itoa(tc.id), tc.body)
 	    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	    Th.ServeReply(tc.w, req)
+	    Th.ServeReply(tc.w, csrfRequest(req))
 	    res := tc.w.Result()
 	    defer res.Body.Close()
 
@@ -826,14 +934,1753 @@ func TestServeReply(t *testing.T) {
     }
 }
 
+func TestServePostPruning(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    sports, err := Th.q.GetBoardByName(context.Background(), "sports")
+    if err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+    tech, err := Th.q.GetBoardByName(context.Background(), "tech")
+    if err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    // filling sports up to its limit and leaving tech with a single thread
+
+    for i := 0; i < int(sports.MaxThreads); i++ {
+	if _, err := Th.q.CreateThread(context.Background(), sqlc.CreateThreadParams{
+	    Title: "This is a sports title",
+	    Comment: "This is a sports comment",
+	    Date: time.Now(),
+	    LastBumpedAt: time.Now(),
+	    BoardID: sports.BoardID,
+	}); err != nil {
+	    t.Errorf("Expected no errors, got %v", err)
+	}
+    }
+
+    if _, err := Th.q.CreateThread(context.Background(), sqlc.CreateThreadParams{
+	Title: "This is the only tech title",
+	Comment: "This is the only tech comment",
+	Date: time.Now(),
+	LastBumpedAt: time.Now(),
+	BoardID: tech.BoardID,
+    }); err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+
+    testCases := []struct {
+	name	string
+	board	sqlc.Board
+	want	int64
+    }{
+	{
+	    name: "quiet board keeps its threads",
+	    board: tech,
+	    want: 2,
+	},
+	{
+	    name: "full board prunes its oldest thread",
+	    board: sports,
+	    want: int64(sports.MaxThreads),
+	},
+    }
+
+    for _, tc := range testCases {
+	t.Run(tc.name, func(t *testing.T) {
+	    w := httptest.NewRecorder()
+	    req := httptest.NewRequest(http.MethodPost, "/post/" + tc.board.Name, bytes.NewReader([]byte("title=a+new+title&comment=a+new+comment")))
+	    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	    Th.ServePost(w, csrfRequest(req))
+
+	    nr, err := Th.q.CountBoardThreads(context.Background(), tc.board.BoardID)
+	    if err != nil {
+		t.Errorf("Expected no errors, got %v", err)
+	    }
+
+	    if nr != tc.want {
+		t.Errorf("expected %d threads on %s, got %d", tc.want, tc.board.Name, nr)
+	    }
+	})
+    }
+}
+
+func TestServePostConcurrent(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    tech, err := Th.q.GetBoardByName(context.Background(), "tech")
+    if err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    if _, err := Th.q.UpdateBoardLimits(context.Background(), sqlc.UpdateBoardLimitsParams{
+	MaxThreads: 5,
+	MaxReplies: tech.MaxReplies,
+	BumpLimit: tech.BumpLimit,
+	MaxComment: tech.MaxComment,
+	BoardID: tech.BoardID,
+    }); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    // firing many more posts than the board can hold at the same time
+
+    var wg sync.WaitGroup
+    paths := make(chan string, 30)
+
+    for i := 0; i < 30; i++ {
+	wg.Add(1)
+	go func() {
+	    defer wg.Done()
+
+	    w := httptest.NewRecorder()
+	    req := httptest.NewRequest(http.MethodPost, "/post/tech", bytes.NewReader([]byte("title=a+parallel+title&comment=a+parallel+comment")))
+	    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	    Th.ServePost(w, csrfRequest(req))
+
+	    url, err := w.Result().Location()
+	    if err != nil {
+		paths <- err.Error()
+		return
+	    }
+	    paths <- url.Path
+	}()
+    }
+
+    wg.Wait()
+    close(paths)
+
+    for path := range paths {
+	if path != "/board/tech" {
+	    t.Errorf("expected every post to redirect to /board/tech, got %s", path)
+	}
+    }
+
+    nr, err := Th.q.CountBoardThreads(context.Background(), tech.BoardID)
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+
+    if nr != 5 {
+	t.Errorf("expected the board to hold exactly 5 threads, got %d", nr)
+    }
+}
+
+func TestServeReplyConcurrent(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    tech, err := Th.q.GetBoardByName(context.Background(), "tech")
+    if err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    if _, err := Th.q.UpdateBoardLimits(context.Background(), sqlc.UpdateBoardLimitsParams{
+	MaxThreads: tech.MaxThreads,
+	MaxReplies: 5,
+	BumpLimit: tech.BumpLimit,
+	MaxComment: tech.MaxComment,
+	BoardID: tech.BoardID,
+    }); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    res, err := Th.q.CreateThread(context.Background(), sqlc.CreateThreadParams{
+	Title: "This thread is about to die",
+	Comment: "This is the comment of a thread that is about to die",
+	Date: time.Now(),
+	LastBumpedAt: time.Now(),
+	BoardID: tech.BoardID,
+    })
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+
+    threadID, err := res.LastInsertId()
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+    id := strconv.Itoa(int(threadID))
+
+    // replying many more times than the thread can hold at the same time
+
+    var wg sync.WaitGroup
+    paths := make(chan string, 30)
+
+    for i := 0; i < 30; i++ {
+	wg.Add(1)
+	go func() {
+	    defer wg.Done()
+
+	    w := httptest.NewRecorder()
+	    req := httptest.NewRequest(http.MethodPost, "/reply/" + id, bytes.NewReader([]byte("comment=a+parallel+reply")))
+	    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	    Th.ServeReply(w, csrfRequest(req))
+
+	    url, err := w.Result().Location()
+	    if err != nil {
+		paths <- err.Error()
+		return
+	    }
+	    paths <- url.Path
+	}()
+    }
+
+    wg.Wait()
+    close(paths)
+
+    counts := map[string]int{}
+    for path := range paths {
+	counts[path]++
+    }
+
+    if counts["/thread/" + id] != 5 {
+	t.Errorf("expected 5 replies to be stored, got %d", counts["/thread/" + id])
+    }
+    if counts["/kill/" + id] != 1 {
+	t.Errorf("expected the thread to be killed once, got %d", counts["/kill/" + id])
+    }
+    if counts["/error/410"] != 30 - 5 - 1 {
+	t.Errorf("expected the remaining replies to find the thread dead, got %d", counts["/error/410"])
+    }
+    if counts["/error/500"] != 0 {
+	t.Errorf("expected no internal server errors, got %d", counts["/error/500"])
+    }
+}
+
+func TestServeArchive(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    tech, err := Th.q.GetBoardByName(context.Background(), "tech")
+    if err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    if _, err := Th.q.UpdateBoardLimits(context.Background(), sqlc.UpdateBoardLimitsParams{
+	MaxThreads: tech.MaxThreads,
+	MaxReplies: 1,
+	BumpLimit: tech.BumpLimit,
+	MaxComment: tech.MaxComment,
+	BoardID: tech.BoardID,
+    }); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    res, err := Th.q.CreateThread(context.Background(), sqlc.CreateThreadParams{
+	Title: "This thread is going to the archive",
+	Comment: "This is the comment of a thread that is going to the archive",
+	Date: time.Now(),
+	LastBumpedAt: time.Now(),
+	BoardID: tech.BoardID,
+    })
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+
+    threadID, err := res.LastInsertId()
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+    id := strconv.Itoa(int(threadID))
+
+    // the first reply fills the thread and the second one kills it
+
+    for _, want := range []string{"/thread/" + id, "/kill/" + id} {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/reply/" + id, bytes.NewReader([]byte("comment=a+reply")))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	Th.ServeReply(w, csrfRequest(req))
+
+	url, err := w.Result().Location()
+	if err != nil {
+	    t.Errorf("Expected no errors, got %v", err)
+	}
+	if url.Path != want {
+	    t.Errorf("expected path to be " + want + " but got " + url.Path)
+	}
+    }
+
+    w := httptest.NewRecorder()
+    Th.ServeThread(w, httptest.NewRequest(http.MethodGet, "/thread/" + id, nil))
+    if w.Result().StatusCode != http.StatusOK {
+	t.Errorf("expected archived thread to still be served, got %d", w.Result().StatusCode)
+    }
+    if strings.Contains(w.Body.String(), "/reply/" + id) {
+	t.Errorf("expected archived thread to not link to the reply form")
+    }
+
+    w = httptest.NewRecorder()
+    Th.ServeArchive(w, httptest.NewRequest(http.MethodGet, "/archive/tech", nil))
+    if !strings.Contains(w.Body.String(), "/thread/" + id) {
+	t.Errorf("expected archived thread to be listed in the archive")
+    }
+
+    // the archive is paged like the board, newest first
+    res, err = Th.q.CreateThread(context.Background(), sqlc.CreateThreadParams{
+	Title: "This thread is going to the archive too",
+	Comment: "This is the comment of a newer archived thread",
+	Date: time.Now().Add(time.Minute),
+	LastBumpedAt: time.Now().Add(time.Minute),
+	BoardID: tech.BoardID,
+    })
+    if err != nil {
+	t.Fatalf("Expected no errors, got %v", err)
+    }
+    newerID, err := res.LastInsertId()
+    if err != nil {
+	t.Fatalf("Expected no errors, got %v", err)
+    }
+    if _, err := retireThread(Th.q, int32(newerID)); err != nil {
+	t.Fatalf("Expected no errors, got %v", err)
+    }
+    newer := strconv.Itoa(int(newerID))
+
+    h := NewHandler(Th.db, Config{PageSize: 1})
+    testCases := []struct {
+	page   string
+	shown  string
+	hidden string
+    }{
+	{page: "1", shown: newer, hidden: id},
+	{page: "2", shown: id, hidden: newer},
+    }
+
+    for _, tc := range testCases {
+	w = httptest.NewRecorder()
+	h.ServeArchive(w, httptest.NewRequest(http.MethodGet, "/archive/tech?page=" + tc.page, nil))
+	body := w.Body.String()
+	if !strings.Contains(body, "/thread/" + tc.shown + "\"") || strings.Contains(body, "/thread/" + tc.hidden + "\"") {
+	    t.Errorf("expected page %s of the archive to list only thread %s", tc.page, tc.shown)
+	}
+    }
+
+    w = httptest.NewRecorder()
+    h.ServeArchive(w, httptest.NewRequest(http.MethodGet, "/archive/tech?page=3", nil))
+    if w.Header().Get("Location") != "/error/404" {
+	t.Errorf("expected a page past the archive not to be found, got %q", w.Header().Get("Location"))
+    }
+
+    w = httptest.NewRecorder()
+    Th.ServeBoard(w, httptest.NewRequest(http.MethodGet, "/board/tech", nil))
+    if strings.Contains(w.Body.String(), "/thread/" + id + "\"") {
+	t.Errorf("expected archived thread to not be listed on the board")
+    }
+}
+
+func TestServeReplyBump(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    tech, err := Th.q.GetBoardByName(context.Background(), "tech")
+    if err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    if _, err := Th.q.UpdateBoardLimits(context.Background(), sqlc.UpdateBoardLimitsParams{
+	MaxThreads: tech.MaxThreads,
+	MaxReplies: tech.MaxReplies,
+	BumpLimit: 1,
+	MaxComment: tech.MaxComment,
+	BoardID: tech.BoardID,
+    }); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    ids := []string{}
+    for i, title := range []string{"The older thread", "The newer thread"} {
+	date := time.Now().Add(time.Duration(i - 2) * time.Hour)
+	res, err := Th.q.CreateThread(context.Background(), sqlc.CreateThreadParams{
+	    Title: title,
+	    Comment: "This is the comment of " + strings.ToLower(title),
+	    Date: date,
+	    LastBumpedAt: date,
+	    BoardID: tech.BoardID,
+	})
+	if err != nil {
+	    t.Errorf("Expected no errors, got %v", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+	    t.Errorf("Expected no errors, got %v", err)
+	}
+	ids = append(ids, strconv.Itoa(int(id)))
+    }
+
+    testCases := []struct {
+	name   string
+	reply  string
+	first  string
+    }{
+	{name: "bumped", reply: ids[0], first: ids[0]},
+	{name: "bumped back", reply: ids[1], first: ids[1]},
+	{name: "past the bump limit", reply: ids[0], first: ids[1]},
+    }
 
+    for _, tc := range testCases {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/reply/" + tc.reply, bytes.NewReader([]byte("comment=a+bump")))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	Th.ServeReply(w, csrfRequest(req))
 
+	threads, err := Th.q.GetBoardThreads(context.Background(), sqlc.GetBoardThreadsParams{BoardID: tech.BoardID, Limit: DefaultPageSize, Offset: 0})
+	if err != nil {
+	    t.Errorf("Expected no errors, got %v", err)
+	}
+	if len(threads) != 2 || strconv.Itoa(int(threads[0].ThreadID)) != tc.first {
+	    t.Errorf("%s: expected thread %s to be at the top of the board", tc.name, tc.first)
+	}
+    }
+}
 
+func TestServeReplySage(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
 
+    ids := []string{}
+    for i, title := range []string{"The older thread", "The newer thread"} {
+	date := time.Now().Add(time.Duration(i - 2) * time.Hour)
+	res, err := Th.q.CreateThread(context.Background(), sqlc.CreateThreadParams{
+	    Title: title,
+	    Comment: "This is the comment of " + strings.ToLower(title),
+	    Date: date,
+	    LastBumpedAt: date,
+	    BoardID: 3,
+	})
+	if err != nil {
+	    t.Errorf("Expected no errors, got %v", err)
+	}
 
+	id, err := res.LastInsertId()
+	if err != nil {
+	    t.Errorf("Expected no errors, got %v", err)
+	}
+	ids = append(ids, strconv.Itoa(int(id)))
+    }
 
+    w := httptest.NewRecorder()
+    req := httptest.NewRequest(http.MethodPost, "/reply/" + ids[0], bytes.NewReader([]byte("comment=a+quiet+reply&sage=on")))
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    Th.ServeReply(w, csrfRequest(req))
 
+    threads, err := Th.q.GetBoardThreads(context.Background(), sqlc.GetBoardThreadsParams{BoardID: 3, Limit: DefaultPageSize, Offset: 0})
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+    if len(threads) != 2 || strconv.Itoa(int(threads[0].ThreadID)) != ids[1] {
+	t.Errorf("expected a saged reply to not bump the thread")
+    }
 
+    w = httptest.NewRecorder()
+    Th.ServeThread(w, httptest.NewRequest(http.MethodGet, "/thread/" + ids[0], nil))
+    if !strings.Contains(w.Body.String(), "<span class=\"sage\">sage</span>") {
+	t.Errorf("expected the saged reply to be marked")
+    }
+}
 
+func TestServeBoardPages(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
 
+    h := NewHandler(Th.db, Config{PageSize: 2})
 
+    for i := 0; i < 5; i++ {
+	date := time.Now().Add(time.Duration(i) * time.Minute)
+	if _, err := Th.q.CreateThread(context.Background(), sqlc.CreateThreadParams{
+	    Title: "Thread number " + strconv.Itoa(i),
+	    Comment: "This is one of many threads",
+	    Date: date,
+	    LastBumpedAt: date,
+	    BoardID: 3,
+	}); err != nil {
+	    t.Errorf("Expected no errors, got %v", err)
+	}
+    }
+
+    board, err := Th.q.GetBoard(context.Background(), 3)
+    if err != nil {
+	t.Errorf("Expected no error, got %v", err)
+    }
+
+    testCases := []struct {
+	name     string
+	page     int
+	previous int
+	next     int
+    }{
+	{name: "first", page: 1, previous: 0, next: 2},
+	{name: "middle", page: 2, previous: 1, next: 3},
+	{name: "last", page: 3, previous: 2, next: 0},
+    }
+
+    for _, tc := range testCases {
+	t.Run(tc.name, func(t *testing.T){
+	    data, err := utils.GetBoardData(Th.q, board, tc.page, 2, DefaultPreviews)
+	    if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	    }
+	    if data.Page.Previous != tc.previous || data.Page.Next != tc.next {
+		t.Errorf("expected previous %d and next %d, got %d and %d", tc.previous, tc.next, data.Page.Previous, data.Page.Next)
+	    }
+
+	    ts, err := stringTemplate(utils.Serve("board"), data)
+	    if err != nil {
+		t.Errorf("Expected no errors, got %v", err)
+	    }
+
+	    w := httptest.NewRecorder()
+	    h.ServeBoard(w, httptest.NewRequest(http.MethodGet, "/board/tech?page=" + strconv.Itoa(tc.page), nil))
+	    if w.Body.String() != ts {
+		t.Errorf("expected response to be equal to template string")
+	    }
+	})
+    }
+
+    for _, page := range []string{"4", "0", "abc"} {
+	w := httptest.NewRecorder()
+	h.ServeBoard(w, httptest.NewRequest(http.MethodGet, "/board/tech?page=" + page, nil))
+
+	url, err := w.Result().Location()
+	if err != nil {
+	    t.Errorf("Expected no errors, got %v", err)
+	}
+	if url.Path != "/error/404" {
+	    t.Errorf("page %s: expected path to be /error/404 but got %s", page, url.Path)
+	}
+    }
+}
+
+func TestServeCatalog(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    ids := []int32{}
+    for i, title := range []string{"The busy thread", "The quiet thread"} {
+	date := time.Now().Add(time.Duration(i - 2) * time.Hour)
+	res, err := Th.q.CreateThread(context.Background(), sqlc.CreateThreadParams{
+	    Title: title,
+	    Comment: "This is the comment of " + strings.ToLower(title),
+	    Date: date,
+	    LastBumpedAt: date,
+	    BoardID: 3,
+	})
+	if err != nil {
+	    t.Errorf("Expected no errors, got %v", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+	    t.Errorf("Expected no errors, got %v", err)
+	}
+	ids = append(ids, int32(id))
+    }
+
+    for i := 0; i < 2; i++ {
+	if _, err := Th.q.CreateReply(context.Background(), sqlc.CreateReplyParams{
+	    Comment: "This is a reply",
+	    Date: time.Now(),
+	    ThreadID: ids[0],
+	}); err != nil {
+	    t.Errorf("Expected no errors, got %v", err)
+	}
+    }
+
+    board, err := Th.q.GetBoard(context.Background(), 3)
+    if err != nil {
+	t.Errorf("Expected no error, got %v", err)
+    }
+
+    // the replies did not bump the thread, its last activity is theirs
+    data, err := utils.GetCatalogData(Th.q, board, "")
+    if err != nil {
+	t.Errorf("Expected no error, got %v", err)
+    }
+    for _, thread := range data.Threads {
+	if thread.ThreadID == ids[0] && !thread.LastActivity.After(thread.LastBumpedAt) {
+	    t.Errorf("expected the last activity of thread %d to be its latest reply", ids[0])
+	}
+    }
+
+    testCases := []struct {
+	name  string
+	sort  string
+	first int32
+    }{
+	{name: "bump", sort: "", first: ids[1]},
+	{name: "date", sort: "date", first: ids[1]},
+	{name: "replies", sort: "replies", first: ids[0]},
+    }
+
+    for _, tc := range testCases {
+	t.Run(tc.name, func(t *testing.T){
+	    data, err := utils.GetCatalogData(Th.q, board, tc.sort)
+	    if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	    }
+	    if len(data.Threads) != 2 || data.Threads[0].ThreadID != tc.first {
+		t.Errorf("expected thread %d to come first", tc.first)
+	    }
+
+	    ts, err := stringTemplate(utils.Serve("catalog"), data)
+	    if err != nil {
+		t.Errorf("Expected no errors, got %v", err)
+	    }
+
+	    w := httptest.NewRecorder()
+	    Th.ServeBoard(w, httptest.NewRequest(http.MethodGet, "/board/tech/catalog?sort=" + tc.sort, nil))
+	    if w.Body.String() != ts {
+		t.Errorf("expected response to be equal to template string")
+	    }
+	})
+    }
+
+    t.Run("redirect", func(t *testing.T){
+	w := httptest.NewRecorder()
+	Th.ServeBoard(w, httptest.NewRequest(http.MethodGet, "/board/tech/nothing", nil))
+
+	url, err := w.Result().Location()
+	if err != nil {
+	    t.Errorf("Expected no errors, got %v", err)
+	}
+	if url.Path != "/error/404" {
+	    t.Errorf("expected path to be /error/404 but got " + url.Path)
+	}
+    })
+}
+
+func TestServeBoardPreviews(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    replies := []int{5, 2, 0}
+    ids := []int32{}
+    for i, nr := range replies {
+	date := time.Now().Add(time.Duration(i - 3) * time.Hour)
+	res, err := Th.q.CreateThread(context.Background(), sqlc.CreateThreadParams{
+	    Title: "A thread with " + strconv.Itoa(nr) + " replies",
+	    Comment: "This is a thread with some replies",
+	    Date: date,
+	    LastBumpedAt: date,
+	    BoardID: 3,
+	})
+	if err != nil {
+	    t.Errorf("Expected no errors, got %v", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+	    t.Errorf("Expected no errors, got %v", err)
+	}
+	ids = append(ids, int32(id))
+
+	for j := 0; j < nr; j++ {
+	    if _, err := Th.q.CreateReply(context.Background(), sqlc.CreateReplyParams{
+		Comment: "Reply number " + strconv.Itoa(j),
+		Date: date.Add(time.Duration(j) * time.Minute),
+		ThreadID: int32(id),
+	    }); err != nil {
+		t.Errorf("Expected no errors, got %v", err)
+	    }
+	}
+    }
+
+    board, err := Th.q.GetBoard(context.Background(), 3)
+    if err != nil {
+	t.Errorf("Expected no error, got %v", err)
+    }
+
+    data, err := utils.GetBoardData(Th.q, board, 1, DefaultPageSize, 3)
+    if err != nil {
+	t.Errorf("Expected no error, got %v", err)
+    }
+
+    testCases := []struct {
+	name    string
+	shown   int
+	omitted int64
+	last    string
+    }{
+	{name: "more replies than previews", shown: 3, omitted: 2, last: "Reply number 4"},
+	{name: "fewer replies than previews", shown: 2, omitted: 0, last: "Reply number 1"},
+	{name: "no replies", shown: 0, omitted: 0, last: ""},
+    }
+
+    for i, tc := range testCases {
+	var preview models.ThreadPreview
+	for _, thread := range data.Threads {
+	    if thread.ThreadID == ids[i] {
+		preview = thread
+	    }
+	}
+
+	if len(preview.Replies) != tc.shown || preview.Omitted != tc.omitted {
+	    t.Errorf("%s: expected %d replies and %d omitted, got %d and %d", tc.name, tc.shown, tc.omitted, len(preview.Replies), preview.Omitted)
+	    continue
+	}
+	if tc.shown > 0 && preview.Replies[tc.shown - 1].Comment != tc.last {
+	    t.Errorf("%s: expected the last preview to be %q, got %q", tc.name, tc.last, preview.Replies[tc.shown - 1].Comment)
+	}
+    }
+}
+
+func TestServeIndexRecent(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    posted := time.Now().Add(-time.Hour)
+    res, err := Th.q.CreateThread(context.Background(), sqlc.CreateThreadParams{
+	Title: "An older thread",
+	Comment: "This thread was posted an hour ago",
+	Date: posted,
+	LastBumpedAt: posted,
+	BoardID: 1,
+    })
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+    older, err := res.LastInsertId()
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+
+    res, err = Th.q.CreateThread(context.Background(), sqlc.CreateThreadParams{
+	Title: "A newer thread",
+	Comment: "This thread was posted half an hour ago",
+	Date: posted.Add(30 * time.Minute),
+	LastBumpedAt: posted.Add(30 * time.Minute),
+	BoardID: 3,
+    })
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+    newer, err := res.LastInsertId()
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+
+    if _, err := Th.q.CreateReply(context.Background(), sqlc.CreateReplyParams{
+	Comment: "A reply to the older thread",
+	Date: posted.Add(45 * time.Minute),
+	ThreadID: int32(older),
+    }); err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+
+    data, err := utils.GetIndexData(Th.q, 1, 2)
+    if err != nil {
+	t.Errorf("Expected no error, got %v", err)
+    }
+
+    // the reply is the newest post, followed by the newer thread, while the
+    // older thread is pushed onto the next page
+
+    if len(data.Posts) != 2 {
+	t.Fatalf("expected 2 recent posts, got %d", len(data.Posts))
+    }
+    if data.Posts[0].ReplyID == 0 || data.Posts[0].ThreadID != int32(older) || data.Posts[0].BoardName != "sports" {
+	t.Errorf("expected the reply to the older thread to come first")
+    }
+    if data.Posts[1].ReplyID != 0 || data.Posts[1].ThreadID != int32(newer) || data.Posts[1].BoardName != "tech" {
+	t.Errorf("expected the newer thread to come second")
+    }
+    if data.Page.Next != 2 {
+	t.Errorf("expected a next page, got %d", data.Page.Next)
+    }
+
+    ts, err := stringTemplate(utils.Serve("index"), data)
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+
+    w := httptest.NewRecorder()
+    NewHandler(Th.db, Config{RecentCount: 2}).ServeIndex(w, httptest.NewRequest(http.MethodGet, "/", nil))
+    if w.Body.String() != ts {
+	t.Errorf("expected response to be equal to template string")
+    }
+}
+
+func TestServeReplyQuotes(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    res, err := Th.q.CreateThread(context.Background(), sqlc.CreateThreadParams{
+	Title: "A thread to be quoted",
+	Comment: "This thread is going to be quoted",
+	Date: time.Now(),
+	LastBumpedAt: time.Now(),
+	BoardID: 3,
+    })
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+    threadID, err := res.LastInsertId()
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+    id := strconv.Itoa(int(threadID))
+
+    res, err = Th.q.CreateReply(context.Background(), sqlc.CreateReplyParams{
+	Comment: "This reply is going to be quoted",
+	Date: time.Now(),
+	ThreadID: int32(threadID),
+    })
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+    replyID, err := res.LastInsertId()
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+    rid := strconv.Itoa(int(replyID))
+
+    // quotes of a reply, of the thread, of a reply that does not exist and
+    // of the thread under the wrong board
+
+    comment := ">>" + rid + " >>>/tech/" + id + " >>999999999 >>>/sports/" + id
+    form := url.Values{"comment": {comment}}
+    w := httptest.NewRecorder()
+    req := httptest.NewRequest(http.MethodPost, "/reply/" + id, strings.NewReader(form.Encode()))
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    Th.ServeReply(w, csrfRequest(req))
+
+    quotes, err := Th.q.GetThreadQuotes(context.Background(), int32(threadID))
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+    if len(quotes) != 2 {
+	t.Fatalf("expected 2 quotes to be stored, got %d", len(quotes))
+    }
+
+    w = httptest.NewRecorder()
+    Th.ServeThread(w, httptest.NewRequest(http.MethodGet, "/thread/" + id, nil))
+    body := w.Body.String()
+
+    testCases := []struct {
+	name string
+	want string
+	not  bool
+    }{
+	{name: "reply quote", want: "<a class=\"quote\" href=\"/thread/" + id + "#r" + rid + "\">&gt;&gt;" + rid + "</a>"},
+	{name: "thread quote", want: "<a class=\"quote\" href=\"/thread/" + id + "\">&gt;&gt;&gt;/tech/" + id + "</a>"},
+	{name: "missing reply", want: "<a class=\"quote\" href=\"/thread/" + id + "#r999999999\">", not: true},
+	{name: "wrong board", want: "&gt;&gt;&gt;/sports/" + id + "</a>", not: true},
+	{name: "backlink", want: "Quoted by:"},
+    }
+
+    for _, tc := range testCases {
+	if strings.Contains(body, tc.want) == tc.not {
+	    t.Errorf("%s: expected presence of %q to be %v", tc.name, tc.want, !tc.not)
+	}
+    }
+}
+
+func TestServePostQuotes(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    res, err := Th.q.CreateThread(context.Background(), sqlc.CreateThreadParams{
+	Title: "A thread to be quoted",
+	Comment: "This thread is going to be quoted",
+	Date: time.Now(),
+	LastBumpedAt: time.Now(),
+	BoardID: 3,
+    })
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+    quotedID, _ := res.LastInsertId()
+    qid := strconv.Itoa(int(quotedID))
+
+    res, err = Th.q.CreateReply(context.Background(), sqlc.CreateReplyParams{
+	Comment: "This reply is going to be quoted",
+	Date: time.Now(),
+	ThreadID: int32(quotedID),
+    })
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+    replyID, _ := res.LastInsertId()
+    rid := strconv.Itoa(int(replyID))
+
+    // an opening post quoting a reply and a thread
+    form := url.Values{"title": {"A quoting thread"}, "comment": {">>" + rid + " >>>/tech/" + qid}}
+    w := httptest.NewRecorder()
+    req := httptest.NewRequest(http.MethodPost, "/post/tech", strings.NewReader(form.Encode()))
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    Th.ServePost(w, csrfRequest(req))
+
+    threads, err := Th.q.GetBoardThreads(context.Background(), sqlc.GetBoardThreadsParams{BoardID: 3, Limit: DefaultPageSize, Offset: 0})
+    if err != nil || len(threads) != 2 {
+	t.Fatalf("expected 2 threads, got %v", err)
+    }
+    id := strconv.Itoa(int(threads[0].ThreadID))
+
+    quotes, err := Th.q.GetThreadQuotes(context.Background(), threads[0].ThreadID)
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+    if len(quotes) != 2 || quotes[0].ReplyID.Valid || quotes[1].ReplyID.Valid {
+	t.Fatalf("expected 2 quotes of the opening post to be stored, got %v", quotes)
+    }
+
+    // a reply on the quoted thread quoting the new one, shown in the preview
+    form = url.Values{"comment": {">>>/tech/" + id}}
+    w = httptest.NewRecorder()
+    req = httptest.NewRequest(http.MethodPost, "/reply/" + qid, strings.NewReader(form.Encode()))
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    Th.ServeReply(w, csrfRequest(req))
+
+    replyQuote := "<a class=\"quote\" href=\"/thread/" + qid + "#r" + rid + "\">&gt;&gt;" + rid + "</a>"
+    threadQuote := "<a class=\"quote\" href=\"/thread/" + qid + "\">&gt;&gt;&gt;/tech/" + qid + "</a>"
+    previewQuote := "<a class=\"quote\" href=\"/thread/" + id + "\">&gt;&gt;&gt;/tech/" + id + "</a>"
+
+    w = httptest.NewRecorder()
+    Th.ServeThread(w, httptest.NewRequest(http.MethodGet, "/thread/" + id, nil))
+    if body := w.Body.String(); !strings.Contains(body, replyQuote) || !strings.Contains(body, threadQuote) {
+	t.Errorf("expected the opening post to link its quotes")
+    }
+
+    w = httptest.NewRecorder()
+    Th.ServeThread(w, httptest.NewRequest(http.MethodGet, "/thread/" + qid, nil))
+    if !strings.Contains(w.Body.String(), "<a class=\"quote\" href=\"/thread/" + id + "\">&gt;&gt;&gt;/tech/" + id + "</a>") {
+	t.Errorf("expected the quoted thread to link back to the opening post")
+    }
+
+    w = httptest.NewRecorder()
+    Th.ServeBoard(w, httptest.NewRequest(http.MethodGet, "/board/tech", nil))
+    body := w.Body.String()
+    for _, want := range []string{replyQuote, threadQuote, previewQuote} {
+	if !strings.Contains(body, want) {
+	    t.Errorf("expected the board page to link %q", want)
+	}
+    }
+}
+
+// csrfRequest sends a request as the test visitor, with their CSRF token
+// as a cookie and at the end of url encoded forms.
+func csrfRequest(req *http.Request) *http.Request {
+    req.AddCookie(&http.Cookie{Name: csrfCookie, Value: testToken})
+    if req.Method == http.MethodPost && req.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+	body, _ := io.ReadAll(req.Body)
+	body = append(body, "&" + csrfField + "=" + testToken...)
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+    }
+
+    return req
+}
+
+func multipartRequest(path string, fields map[string]string, name string, content []byte) *http.Request {
+    var buff bytes.Buffer
+    mw := multipart.NewWriter(&buff)
+    for k, v := range fields {
+	mw.WriteField(k, v)
+    }
+    mw.WriteField(csrfField, testToken)
+    if name != "" {
+	fw, _ := mw.CreateFormFile("file", name)
+	fw.Write(content)
+    }
+    mw.Close()
+
+    req := httptest.NewRequest(http.MethodPost, path, &buff)
+    req.Header.Set("Content-Type", mw.FormDataContentType())
+    req.AddCookie(&http.Cookie{Name: csrfCookie, Value: testToken})
+    return req
+}
+
+func TestServePostAttachment(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+    Th.cfg.UploadDir = t.TempDir()
+
+    tech, err := Th.q.GetBoardByName(context.Background(), "tech")
+    if err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+    if _, err := Th.q.UpdateBoardFiles(context.Background(), sqlc.UpdateBoardFilesParams{
+	MaxFileSize: 1024,
+	AllowedTypes: "image/png",
+	BoardID: tech.BoardID,
+    }); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    var buff bytes.Buffer
+    if err := png.Encode(&buff, image.NewNRGBA(image.Rect(0, 0, 500, 20))); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+    cat := buff.Bytes()
+    fields := map[string]string{"title": "A thread with a file", "comment": "Look at this"}
+
+    testCases := []struct {
+	name    string
+	file    string
+	content []byte
+	stored  bool
+    }{
+	{name: "png", file: "cat.png", content: cat, stored: true},
+	{name: "text named as png", file: "cat.png", content: []byte("<html><script>alert(1)</script></html>"), stored: false},
+	{name: "too large", file: "cat.png", content: append(cat, make([]byte, 1024)...), stored: false},
+	{name: "no file", file: "", content: nil, stored: true},
+    }
+
+    for _, tc := range testCases {
+	w := httptest.NewRecorder()
+	Th.ServePost(w, multipartRequest("/post/tech", fields, tc.file, tc.content))
+	res := w.Result()
+
+	_, err := res.Location()
+	if (err == nil) != tc.stored {
+	    t.Errorf("%s: expected the thread to be stored to be %v", tc.name, tc.stored)
+	}
+	if !tc.stored && !strings.Contains(w.Body.String(), "error-message") {
+	    t.Errorf("%s: expected the form to show an error", tc.name)
+	}
+    }
+
+    threads, err := Th.q.GetBoardThreads(context.Background(), sqlc.GetBoardThreadsParams{BoardID: tech.BoardID, Limit: DefaultPageSize, Offset: 0})
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+    if len(threads) != 2 {
+	t.Fatalf("expected 2 threads, got %d", len(threads))
+    }
+
+    // the thread without a file is the newest one
+    attachments, err := Th.q.GetThreadAttachments(context.Background(), threads[1].ThreadID)
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+    if len(attachments) != 1 || attachments[0].MimeType != "image/png" || attachments[0].OriginalName != "cat.png" {
+	t.Fatalf("expected the png to be stored, got %v", attachments)
+    }
+    entries, err := os.ReadDir(Th.uploadDir())
+    if err != nil || len(entries) != 2 {
+	t.Errorf("expected only the png and its thumbnail on disk, got %v and %v", entries, err)
+    }
+
+    for _, name := range []string{attachments[0].FileName, attachments[0].ThumbName} {
+	w := httptest.NewRecorder()
+	Th.ServeFile(w, httptest.NewRequest(http.MethodGet, "/files/" + name, nil))
+	res := w.Result()
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "image/png" || res.Header.Get("X-Content-Type-Options") != "nosniff" {
+	    t.Errorf("expected %q to be served as a png, got %d %q", name, res.StatusCode, res.Header.Get("Content-Type"))
+	}
+    }
+
+    thumb, err := png.DecodeConfig(bytes.NewReader(mustReadFile(t, filepath.Join(Th.uploadDir(), attachments[0].ThumbName))))
+    if err != nil || thumb.Width != utils.ThumbnailSize || thumb.Height != 10 {
+	t.Errorf("expected a %dx10 thumbnail, got %v and %v", utils.ThumbnailSize, thumb, err)
+    }
+
+    w := httptest.NewRecorder()
+    Th.ServeBoard(w, httptest.NewRequest(http.MethodGet, "/board/tech", nil))
+    if !strings.Contains(w.Body.String(), "<img src=\"/files/" + attachments[0].ThumbName + "\"") {
+	t.Errorf("expected the board to show the thumbnail")
+    }
+
+    w = httptest.NewRecorder()
+    Th.ServeFile(w, httptest.NewRequest(http.MethodGet, "/files/../main.go", nil))
+    if url, err := w.Result().Location(); err != nil || url.Path != "/error/404" {
+	t.Errorf("expected other paths not to be served")
+    }
+
+    // pruning the thread with the file removes it from the disk
+    if _, err := Th.q.UpdateBoardLimits(context.Background(), sqlc.UpdateBoardLimitsParams{
+	MaxThreads: 2,
+	MaxReplies: tech.MaxReplies,
+	BumpLimit: tech.BumpLimit,
+	MaxComment: tech.MaxComment,
+	BoardID: tech.BoardID,
+    }); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+    Th.ServePost(httptest.NewRecorder(), multipartRequest("/post/tech", fields, "", nil))
+
+    for _, name := range []string{attachments[0].FileName, attachments[0].ThumbName} {
+	if _, err := os.Stat(filepath.Join(Th.uploadDir(), name)); !os.IsNotExist(err) {
+	    t.Errorf("expected %q of the pruned thread to be removed, got %v", name, err)
+	}
+    }
+    if _, err := Th.q.GetAttachment(context.Background(), sqlc.GetAttachmentParams{FileName: attachments[0].FileName, ThumbName: attachments[0].FileName}); err == nil {
+	t.Errorf("expected the attachment of the pruned thread to be deleted")
+    }
+}
+
+func TestServePostDuplicate(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+    Th.cfg.UploadDir = t.TempDir()
+
+    var buff bytes.Buffer
+    if err := png.Encode(&buff, image.NewNRGBA(image.Rect(0, 0, 20, 20))); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+    cat := buff.Bytes()
+    fields := map[string]string{"title": "A thread with a file", "comment": "Look at this"}
+
+    post := func(board string) string {
+	w := httptest.NewRecorder()
+	Th.ServePost(w, multipartRequest("/post/" + board, fields, "cat.png", cat))
+	if _, err := w.Result().Location(); err == nil {
+	    return ""
+	}
+	return w.Body.String()
+    }
+
+    if body := post("tech"); body != "" {
+	t.Fatalf("expected the first post of the file to be stored")
+    }
+    if body := post("tech"); !strings.Contains(body, "This file has already been posted on this board") {
+	t.Errorf("expected the same file to be refused on the same board")
+    }
+
+    // the history outlives the post, a pruned or deleted thread does not
+    // let the file through again
+    tech, err := Th.q.GetBoardThreads(context.Background(), sqlc.GetBoardThreadsParams{BoardID: 3, Limit: DefaultPageSize, Offset: 0})
+    if err != nil || len(tech) != 1 {
+	t.Fatalf("expected a thread on tech, got %v", err)
+    }
+    if _, err := retireThread(Th.q, tech[0].ThreadID); err != nil {
+	t.Fatalf("expected no error, got %v", err)
+    }
+    if body := post("tech"); !strings.Contains(body, "This file has already been posted on this board") {
+	t.Errorf("expected the file of a pruned thread to be refused")
+    }
+    if body := post("sports"); body != "" {
+	t.Errorf("expected the same file to be stored on another board")
+    }
+
+    if _, err := Th.q.UpdateBoardFiles(context.Background(), sqlc.UpdateBoardFilesParams{
+	MaxFileSize: 2097152,
+	AllowedTypes: "image/png",
+	DuplicateWindow: 0,
+	BoardID: 1,
+    }); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+    if body := post("sports"); body != "" {
+	t.Errorf("expected the same file to be stored without a duplicate window")
+    }
+
+    // banning the file by the name it was stored with refuses it everywhere
+    threads, err := Th.q.GetBoardThreads(context.Background(), sqlc.GetBoardThreadsParams{BoardID: 1, Limit: DefaultPageSize, Offset: 0})
+    if err != nil || len(threads) == 0 {
+	t.Fatalf("expected threads on sports, got %v", err)
+    }
+    attachments, err := Th.q.GetThreadAttachments(context.Background(), threads[0].ThreadID)
+    if err != nil || len(attachments) != 1 {
+	t.Fatalf("expected an attachment, got %v", err)
+    }
+
+    // a post that passed the form check before another one stored the
+    // same file is still refused when it is stored
+    now := time.Now()
+    err = Th.createThread(sqlc.CreateThreadParams{
+	Title: "A racing thread",
+	Comment: "Look at this too",
+	Date: now,
+	LastBumpedAt: now,
+	BoardID: 3,
+    }, &sqlc.CreateAttachmentParams{
+	FileName: "racing.png",
+	OriginalName: "cat.png",
+	MimeType: "image/png",
+	Size: int32(len(cat)),
+	Sha256: attachments[0].Sha256,
+	Date: now,
+    }, "192.0.2.1")
+    if utils.ErrorStatus(err) != http.StatusBadRequest {
+	t.Errorf("expected the racing duplicate to be refused with 400, got %v", err)
+    }
+
+    w := httptest.NewRecorder()
+    Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"ban"}, "sha256": {attachments[0].FileName}, "reason": {"spam"}}))
+    if _, err := w.Result().Location(); err != nil {
+	t.Errorf("expected the ban to be stored, got %s", w.Body.String())
+    }
+
+    for _, board := range []string{"sports", "random"} {
+	if body := post(board); !strings.Contains(body, "This file is banned") {
+	    t.Errorf("expected the banned file to be refused on %s", board)
+	}
+    }
+
+    w = httptest.NewRecorder()
+    Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"unban"}, "sha256": {attachments[0].Sha256}}))
+    if body := post("random"); body != "" {
+	t.Errorf("expected the file to be stored once it is unbanned")
+    }
+}
+
+func TestServeDelete(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    hash, err := utils.HashPassword("hunter2")
+    if err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    res, err := Th.q.CreateThread(context.Background(), sqlc.CreateThreadParams{
+	Title: "A thread to delete",
+	Comment: "This is the comment of the thread",
+	Date: time.Now(),
+	LastBumpedAt: time.Now(),
+	BoardID: 3,
+	DeleteHash: hash,
+    })
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+    threadID, _ := res.LastInsertId()
+    id := strconv.Itoa(int(threadID))
+
+    replies := []string{}
+    for _, comment := range []string{"A reply to delete", "A reply quoting it"} {
+	if len(replies) == 1 {
+	    comment = ">>" + replies[0] + " " + comment
+	}
+	form := url.Values{"comment": {comment}, "password": {"hunter2"}}
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/reply/" + id, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	Th.ServeReply(w, csrfRequest(req))
+
+	posted, err := Th.q.GetThreadReplies(context.Background(), int32(threadID))
+	if err != nil || len(posted) != len(replies) + 1 {
+	    t.Fatalf("expected the reply to be stored, got %v", err)
+	}
+	replies = append(replies, strconv.Itoa(int(posted[len(posted) - 1].ReplyID)))
+    }
+
+    remove := func(form url.Values) string {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/delete/" + id, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	Th.ServeDelete(w, csrfRequest(req))
+
+	url, err := w.Result().Location()
+	if err != nil {
+	    return ""
+	}
+	return url.Path
+    }
+
+    testCases := []struct {
+	name string
+	form url.Values
+	want string
+    }{
+	{name: "wrong password", form: url.Values{"reply_id": {replies[0]}, "password": {"hunter3"}}, want: "/error/403"},
+	{name: "reply of another thread", form: url.Values{"reply_id": {"999999999"}, "password": {"hunter2"}}, want: "/error/404"},
+	{name: "reply", form: url.Values{"reply_id": {replies[0]}, "password": {"hunter2"}}, want: "/thread/" + id},
+	{name: "deleted reply", form: url.Values{"reply_id": {replies[0]}, "password": {"hunter2"}}, want: "/error/404"},
+    }
+
+    for _, tc := range testCases {
+	if got := remove(tc.form); got != tc.want {
+	    t.Errorf("%s: expected path to be %s but got %s", tc.name, tc.want, got)
+	}
+    }
+
+    w := httptest.NewRecorder()
+    Th.ServeThread(w, httptest.NewRequest(http.MethodGet, "/thread/" + id, nil))
+    body := w.Body.String()
+    if !strings.Contains(body, "[deleted]") || strings.Contains(body, "A reply to delete") {
+	t.Errorf("expected the deleted reply to be replaced by a placeholder")
+    }
+    if !strings.Contains(body, "<a class=\"quote\" href=\"/thread/" + id + "#r" + replies[0] + "\">") {
+	t.Errorf("expected the quote of the deleted reply to stay")
+    }
+    if !strings.Contains(body, "#r" + replies[1] + "\">&gt;&gt;" + replies[1] + "</a>") {
+	t.Errorf("expected the quoting reply to be linked back")
+    }
+
+    // a deleted reply no longer links back to the posts it quoted
+    if got := remove(url.Values{"reply_id": {replies[1]}, "password": {"hunter2"}}); got != "/thread/" + id {
+	t.Errorf("expected path to be /thread/%s but got %s", id, got)
+    }
+    w = httptest.NewRecorder()
+    Th.ServeThread(w, httptest.NewRequest(http.MethodGet, "/thread/" + id, nil))
+    if strings.Contains(w.Body.String(), "#r" + replies[1] + "\">&gt;&gt;" + replies[1] + "</a>") {
+	t.Errorf("expected the backlink of the deleted reply to be gone")
+    }
+
+    if got := remove(url.Values{"password": {"hunter2"}}); got != "/board/tech" {
+	t.Errorf("expected path to be /board/tech but got %s", got)
+    }
+
+    // the thread is archived like a pruned one, the replies of others and
+    // the quotes into it stay readable
+    w = httptest.NewRecorder()
+    Th.ServeThread(w, httptest.NewRequest(http.MethodGet, "/thread/" + id, nil))
+    if _, err := w.Result().Location(); err == nil || !strings.Contains(w.Body.String(), "A reply quoting it") {
+	t.Errorf("expected the replies of the deleted thread to stay readable")
+    }
+
+    thread, err := Th.q.GetThread(context.Background(), int32(threadID))
+    if err != nil || thread.Status != sqlc.ThreadsStatusArchived {
+	t.Errorf("expected the thread to be archived, got %v", err)
+    }
+}
+
+func TestServePostNames(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    form := url.Values{"title": {"A named thread"}, "comment": {"This is the comment"}, "name": {"alice#x"}}
+    w := httptest.NewRecorder()
+    req := httptest.NewRequest(http.MethodPost, "/post/tech", strings.NewReader(form.Encode()))
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    Th.ServePost(w, csrfRequest(req))
+
+    // the name of the poster must not take the place of the board name
+    if w.Header().Get("Location") != "/board/tech" {
+	t.Errorf("expected a redirect to %q, got %q", "/board/tech", w.Header().Get("Location"))
+    }
+
+    threads, err := Th.q.GetBoardThreads(context.Background(), sqlc.GetBoardThreadsParams{BoardID: 3, Limit: DefaultPageSize, Offset: 0})
+    if err != nil || len(threads) != 1 {
+	t.Fatalf("expected 1 thread, got %v", err)
+    }
+    if threads[0].Name != "alice" || threads[0].Tripcode != utils.Tripcode("x") {
+	t.Errorf("expected the thread to keep the name and tripcode, got %q and %q", threads[0].Name, threads[0].Tripcode)
+    }
+}
+
+func TestServeReplyNames(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    res, err := Th.q.CreateThread(context.Background(), sqlc.CreateThreadParams{
+	Title: "A thread with names",
+	Comment: "This is the comment of the thread",
+	Date: time.Now(),
+	LastBumpedAt: time.Now(),
+	BoardID: 3,
+    })
+    if err != nil {
+	t.Errorf("Expected no errors, got %v", err)
+    }
+    threadID, _ := res.LastInsertId()
+    id := strconv.Itoa(int(threadID))
+
+    reply := func(name string) {
+	form := url.Values{"comment": {"a reply"}, "name": {name}}
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/reply/" + id, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	Th.ServeReply(w, csrfRequest(req))
+    }
+
+    reply("alice#secret")
+    reply("")
+
+    // once the board forces anonymity names are dropped
+    w := httptest.NewRecorder()
+    Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"anon"}, "board_id": {"3"}, "force_anon": {"on"}}))
+    reply("bob#hunter2")
+
+    replies, err := Th.q.GetThreadReplies(context.Background(), int32(threadID))
+    if err != nil || len(replies) != 3 {
+	t.Fatalf("expected 3 replies, got %d and %v", len(replies), err)
+    }
+
+    testCases := []struct {
+	name     string
+	want     string
+	tripcode string
+    }{
+	{name: "name and tripcode", want: "alice", tripcode: utils.Tripcode("secret")},
+	{name: "anonymous", want: "", tripcode: ""},
+	{name: "forced anonymity", want: "", tripcode: ""},
+    }
+
+    for i, tc := range testCases {
+	if replies[i].Name != tc.want || replies[i].Tripcode != tc.tripcode {
+	    t.Errorf("%s: expected %q and %q, got %q and %q", tc.name, tc.want, tc.tripcode, replies[i].Name, replies[i].Tripcode)
+	}
+    }
+
+    w = httptest.NewRecorder()
+    Th.ServeThread(w, httptest.NewRequest(http.MethodGet, "/thread/" + id, nil))
+    if !strings.Contains(w.Body.String(), "<span class=\"name\">alice</span> <span class=\"tripcode\">!" + utils.Tripcode("secret") + "</span>") {
+	t.Errorf("expected the thread to show the name and tripcode")
+    }
+
+    w = httptest.NewRecorder()
+    Th.ServeReply(w, httptest.NewRequest(http.MethodGet, "/reply/" + id, nil))
+    if strings.Contains(w.Body.String(), "name=\"name\"") {
+	t.Errorf("expected the reply form to have no name field when anonymity is forced")
+    }
+}
+
+func TestServeThreadPosterIDs(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    w := httptest.NewRecorder()
+    Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"ids"}, "board_id": {"3"}, "poster_ids": {"on"}}))
+
+    form := url.Values{"title": {"A thread with IDs"}, "comment": {"This is the comment"}}
+    w = httptest.NewRecorder()
+    req := httptest.NewRequest(http.MethodPost, "/post/tech", strings.NewReader(form.Encode()))
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    req.RemoteAddr = "192.0.2.1:1234"
+    Th.ServePost(w, csrfRequest(req))
+
+    threads, err := Th.q.GetBoardThreads(context.Background(), sqlc.GetBoardThreadsParams{BoardID: 3, Limit: DefaultPageSize, Offset: 0})
+    if err != nil || len(threads) != 1 {
+	t.Fatalf("expected 1 thread, got %v", err)
+    }
+    thread := threads[0]
+    id := strconv.Itoa(int(thread.ThreadID))
+
+    for _, ip := range []string{"192.0.2.1:5678", "192.0.2.2:1234"} {
+	form := url.Values{"comment": {"a reply"}}
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/reply/" + id, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = ip
+	Th.ServeReply(w, csrfRequest(req))
+    }
+
+    replies, err := Th.q.GetThreadReplies(context.Background(), thread.ThreadID)
+    if err != nil || len(replies) != 2 {
+	t.Fatalf("expected 2 replies, got %v", err)
+    }
+
+    want := utils.PosterID(Th.cfg.Secret, "192.0.2.1", thread.ThreadID)
+    if thread.PosterID != want || replies[0].PosterID != want {
+	t.Errorf("expected the same poster to keep the ID %q, got %q and %q", want, thread.PosterID, replies[0].PosterID)
+    }
+    if replies[1].PosterID == want || replies[1].PosterID == "" {
+	t.Errorf("expected another poster to get another ID, got %q", replies[1].PosterID)
+    }
+
+    w = httptest.NewRecorder()
+    Th.ServeThread(w, httptest.NewRequest(http.MethodGet, "/thread/" + id, nil))
+    if !strings.Contains(w.Body.String(), "<span class=\"poster-id\">ID: " + want + "</span> <span class=\"poster-count\">2 posts</span>") {
+	t.Errorf("expected the thread to show the ID with its post count")
+    }
+}
+
+func TestServePostRateLimit(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    w := httptest.NewRecorder()
+    Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"rates"}, "board_id": {"3"}, "thread_burst": {"2"}, "thread_interval": {"300"}, "reply_burst": {"1"}, "reply_interval": {"60"}}))
+
+    post := func(ip string) *httptest.ResponseRecorder {
+	form := url.Values{"title": {"A limited thread"}, "comment": {"This is the comment"}}
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/post/tech", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = ip
+	Th.ServePost(w, csrfRequest(req))
+	return w
+    }
+
+    for i := 0; i < 2; i++ {
+	if w := post("192.0.2.1:1234"); w.Header().Get("Location") != "/board/tech" {
+	    t.Errorf("expected thread %d of the burst to be posted, got %q", i + 1, w.Header().Get("Location"))
+	}
+    }
+    if w := post("192.0.2.1:5678"); w.Header().Get("Location") != "/error/429" {
+	t.Errorf("expected a third thread to be limited, got %q", w.Header().Get("Location"))
+    }
+    if w := post("192.0.2.2:1234"); w.Header().Get("Location") != "/board/tech" {
+	t.Errorf("expected another poster not to be limited, got %q", w.Header().Get("Location"))
+    }
+
+    threads, err := Th.q.GetBoardThreads(context.Background(), sqlc.GetBoardThreadsParams{BoardID: 3, Limit: DefaultPageSize, Offset: 0})
+    if err != nil || len(threads) != 3 {
+	t.Fatalf("expected 3 threads, got %v", err)
+    }
+    id := strconv.Itoa(int(threads[0].ThreadID))
+
+    // replies have their own limit, the threads did not use it up
+    for i, want := range []string{"/thread/" + id, "/error/429"} {
+	form := url.Values{"comment": {"a reply"}}
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/reply/" + id, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = "192.0.2.1:1234"
+	Th.ServeReply(w, csrfRequest(req))
+	if w.Header().Get("Location") != want {
+	    t.Errorf("expected reply %d to redirect to %q, got %q", i + 1, want, w.Header().Get("Location"))
+	}
+    }
+
+    w = httptest.NewRecorder()
+    Th.ServeError(w, httptest.NewRequest(http.MethodGet, "/error/429", nil))
+    if !strings.Contains(w.Body.String(), "You are posting too fast") {
+	t.Errorf("expected the error page to ask the poster to slow down")
+    }
+}
+
+func TestMemoryLimiterCap(t *testing.T) {
+    l := newMemoryLimiter()
+    now := time.Now()
+
+    // none of the buckets refills before the store is full
+    for i := 0; i < maxMemoryBuckets; i++ {
+	if ok, _ := l.allow(strconv.Itoa(i), 2, 3600, now.Add(time.Duration(i) * time.Microsecond)); !ok {
+	    t.Fatalf("expected the first post of poster %d to be allowed", i)
+	}
+    }
+
+    l.allow("0", 2, 3600, now.Add(time.Second))
+    l.allow("new", 2, 3600, now.Add(time.Second))
+    if len(l.buckets) != maxMemoryBuckets {
+	t.Errorf("expected the store to stay at %d buckets, got %d", maxMemoryBuckets, len(l.buckets))
+    }
+    if _, ok := l.buckets["1"]; ok {
+	t.Errorf("expected the bucket used the longest ago to be dropped")
+    }
+}
+
+func TestServePostRateLimitDatabase(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    w := httptest.NewRecorder()
+    Th.ServeAdmin(w, adminRequest(http.MethodPost, url.Values{"action": {"rates"}, "board_id": {"3"}, "thread_burst": {"2"}, "thread_interval": {"300"}, "reply_burst": {"5"}, "reply_interval": {"0"}}))
+
+    // two instances of the server share the buckets through the database
+    cfg := Config{Secret: Th.cfg.Secret, RateLimitStore: RateLimitDatabase}
+    instances := []*Handler{NewHandler(Th.db, cfg), NewHandler(Th.db, cfg)}
+
+    post := func(h *Handler, ip string) string {
+	form := url.Values{"title": {"A limited thread"}, "comment": {"This is the comment"}}
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/post/tech", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = ip
+	h.ServePost(w, csrfRequest(req))
+	return w.Header().Get("Location")
+    }
+
+    for i, h := range instances {
+	if got := post(h, "192.0.2.1:1234"); got != "/board/tech" {
+	    t.Errorf("expected thread %d of the burst to be posted, got %q", i + 1, got)
+	}
+    }
+    for i, h := range instances {
+	if got := post(h, "192.0.2.1:1234"); got != "/error/429" {
+	    t.Errorf("expected instance %d to see the burst used up, got %q", i + 1, got)
+	}
+    }
+    if got := post(instances[0], "192.0.2.2:1234"); got != "/board/tech" {
+	t.Errorf("expected another poster not to be limited, got %q", got)
+    }
+
+    // sports has its limits turned off, its posts never reach the store
+    form := url.Values{"title": {"An unlimited thread"}, "comment": {"This is the comment"}}
+    req := httptest.NewRequest(http.MethodPost, "/post/sports", strings.NewReader(form.Encode()))
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    req.RemoteAddr = "192.0.2.1:1234"
+    w = httptest.NewRecorder()
+    instances[0].ServePost(w, csrfRequest(req))
+    if w.Header().Get("Location") != "/board/sports" {
+	t.Errorf("expected the thread on sports to be posted, got %q", w.Header().Get("Location"))
+    }
+
+    var count int
+    if err := Th.db.QueryRow("SELECT COUNT(*) FROM rate_limits WHERE bucket_key LIKE 'thread:3:%'").Scan(&count); err != nil || count != 2 {
+	t.Errorf("expected a bucket for each poster keyed by the board ID, got %d, %v", count, err)
+    }
+    if err := Th.db.QueryRow("SELECT COUNT(*) FROM rate_limits").Scan(&count); err != nil || count != 2 {
+	t.Errorf("expected no bucket for a board without limits, got %d, %v", count, err)
+    }
+
+    // posts racing for the same new bucket must neither deadlock nor take
+    // more than the burst
+    if _, err := Th.db.Exec("DELETE FROM rate_limits"); err != nil {
+	t.Fatalf("expected no error, got %v", err)
+    }
+    var wg sync.WaitGroup
+    allowed := make([]bool, 8)
+    errs := make([]error, 8)
+    for i := range allowed {
+	wg.Add(1)
+	go func(i int) {
+	    defer wg.Done()
+	    allowed[i], errs[i] = instances[i % 2].limits.allow("race", 2, 300, time.Now())
+	}(i)
+    }
+    wg.Wait()
+
+    taken := 0
+    for i := range allowed {
+	if errs[i] != nil {
+	    t.Errorf("expected no error, got %v", errs[i])
+	}
+	if allowed[i] {
+	    taken++
+	}
+    }
+    if taken != 2 {
+	t.Errorf("expected 2 of the racing posts to be allowed, got %d", taken)
+    }
+}
+
+func TestServePostCSRF(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    w := httptest.NewRecorder()
+    Th.ServePost(w, httptest.NewRequest(http.MethodGet, "/post/tech", nil))
+    cookies := w.Result().Cookies()
+    if len(cookies) != 1 || cookies[0].Name != csrfCookie || !cookies[0].HttpOnly {
+	t.Fatalf("expected the form to set an http only CSRF cookie, got %v", cookies)
+    }
+    token := cookies[0].Value
+    if !strings.Contains(w.Body.String(), "<input type=\"hidden\" name=\"csrf_token\" value=\"" + token + "\"/>") {
+	t.Errorf("expected the token of the cookie in the form")
+    }
+
+    w = httptest.NewRecorder()
+    Th.ServePost(w, csrfRequest(httptest.NewRequest(http.MethodGet, "/post/tech", nil)))
+    if len(w.Result().Cookies()) != 0 {
+	t.Errorf("expected a visitor with a valid token to keep it")
+    }
+
+    forged, err := utils.NewCSRFToken("not the secret")
+    if err != nil {
+	t.Fatalf("expected no error, got %v", err)
+    }
+    other, err := utils.NewCSRFToken(Th.cfg.Secret)
+    if err != nil {
+	t.Fatalf("expected no error, got %v", err)
+    }
+
+    testCases := []struct {
+	name   string
+	cookie string
+	field  string
+	want   string
+    }{
+	{name: "valid token", cookie: token, field: token, want: "/board/tech"},
+	{name: "no token", cookie: "", field: "", want: "/error/403"},
+	{name: "no cookie", cookie: "", field: token, want: "/error/403"},
+	{name: "another token", cookie: token, field: other, want: "/error/403"},
+	{name: "forged token", cookie: forged, field: forged, want: "/error/403"},
+    }
+
+    for _, tc := range testCases {
+	t.Run(tc.name, func(t *testing.T) {
+	    form := url.Values{"title": {"A cross site thread"}, "comment": {"This is the comment"}, csrfField: {tc.field}}
+	    w := httptest.NewRecorder()
+	    req := httptest.NewRequest(http.MethodPost, "/post/tech", strings.NewReader(form.Encode()))
+	    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	    if tc.cookie != "" {
+		req.AddCookie(&http.Cookie{Name: csrfCookie, Value: tc.cookie})
+	    }
+	    Th.ServePost(w, req)
+
+	    if w.Header().Get("Location") != tc.want {
+		t.Errorf("expected a redirect to %q, got %q", tc.want, w.Header().Get("Location"))
+	    }
+	})
+    }
+
+    threads, err := Th.q.GetBoardThreads(context.Background(), sqlc.GetBoardThreadsParams{BoardID: 3, Limit: DefaultPageSize, Offset: 0})
+    if err != nil || len(threads) != 1 {
+	t.Fatalf("expected only the thread with a valid token, got %v", err)
+    }
+
+    w = httptest.NewRecorder()
+    Th.ServeError(w, httptest.NewRequest(http.MethodGet, "/error/403", nil))
+    if !strings.Contains(w.Body.String(), "Forbidden") {
+	t.Errorf("expected the error page to forbid the post")
+    }
+}
+
+func TestServeReplyCSRF(t *testing.T) {
+    if err := start(); err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+
+    form := url.Values{"title": {"A thread to reply to"}, "comment": {"This is the comment"}}
+    w := httptest.NewRecorder()
+    req := httptest.NewRequest(http.MethodPost, "/post/tech", strings.NewReader(form.Encode()))
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    Th.ServePost(w, csrfRequest(req))
+
+    threads, err := Th.q.GetBoardThreads(context.Background(), sqlc.GetBoardThreadsParams{BoardID: 3, Limit: DefaultPageSize, Offset: 0})
+    if err != nil || len(threads) != 1 {
+	t.Fatalf("expected 1 thread, got %v", err)
+    }
+    id := strconv.Itoa(int(threads[0].ThreadID))
+
+    w = httptest.NewRecorder()
+    Th.ServeReply(w, csrfRequest(httptest.NewRequest(http.MethodGet, "/reply/" + id, nil)))
+    if !strings.Contains(w.Body.String(), "<input type=\"hidden\" name=\"csrf_token\" value=\"" + testToken + "\"/>") {
+	t.Errorf("expected the token of the cookie in the form")
+    }
+
+    // a form posted from another site carries the cookie but not the token
+    form = url.Values{"comment": {"a cross site reply"}}
+    w = httptest.NewRecorder()
+    req = httptest.NewRequest(http.MethodPost, "/reply/" + id, strings.NewReader(form.Encode()))
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    req.AddCookie(&http.Cookie{Name: csrfCookie, Value: testToken})
+    Th.ServeReply(w, req)
+    if w.Header().Get("Location") != "/error/403" {
+	t.Errorf("expected a reply without a token to be forbidden, got %q", w.Header().Get("Location"))
+    }
+
+    w = httptest.NewRecorder()
+    req = httptest.NewRequest(http.MethodPost, "/reply/" + id, strings.NewReader(form.Encode()))
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    Th.ServeReply(w, csrfRequest(req))
+    if w.Header().Get("Location") != "/thread/" + id {
+	t.Errorf("expected a reply with the token to be posted, got %q", w.Header().Get("Location"))
+    }
+
+    replies, err := Th.q.GetThreadReplies(context.Background(), threads[0].ThreadID)
+    if err != nil || len(replies) != 1 {
+	t.Errorf("expected only the reply with the token, got %v", err)
+    }
+}
+
+func mustReadFile(t *testing.T, name string) []byte {
+    b, err := os.ReadFile(name)
+    if err != nil {
+	t.Fatalf("expected no error, got %v", err)
+    }
+
+    return b
+}
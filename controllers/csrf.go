@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/enzdor/gomsg/utils"
+)
+
+// csrfCookie is the cookie the CSRF token of a visitor is kept in and
+// csrfField the form field every form sends it back in.
+const (
+	csrfCookie = "csrf"
+	csrfField = "csrf_token"
+)
+
+// csrfToken returns the CSRF token to put in the forms of a page. A visitor
+// without a token, or with one we did not sign, is given a new one in a
+// cookie, so it must be called before anything is written.
+func (h *Handler) csrfToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if cookie, err := r.Cookie(csrfCookie); err == nil && utils.ValidCSRFToken(h.cfg.Secret, cookie.Value) {
+	    return cookie.Value, nil
+	}
+
+	token, err := utils.NewCSRFToken(h.cfg.Secret)
+	if err != nil {
+	    return "", err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+	    Name: csrfCookie,
+	    Value: token,
+	    Path: "/",
+	    HttpOnly: true,
+	    Secure: r.TLS != nil,
+	    SameSite: http.SameSiteLaxMode,
+	})
+
+	return token, nil
+}
+
+// checkCSRF reports whether a parsed form carries the CSRF token in the
+// cookie of its request.
+func (h *Handler) checkCSRF(r *http.Request) bool {
+	cookie, err := r.Cookie(csrfCookie)
+	if err != nil {
+	    return false
+	}
+
+	return utils.CheckCSRFToken(h.cfg.Secret, cookie.Value, r.PostFormValue(csrfField))
+}
@@ -0,0 +1,329 @@
+package controllers
+
+import (
+	"os"
+	"io"
+	"log"
+	"errors"
+	"regexp"
+	"context"
+	"database/sql"
+	"strconv"
+	"net/http"
+	"crypto/rand"
+	"encoding/hex"
+	"path/filepath"
+	"mime"
+	"time"
+	"mime/multipart"
+
+	"github.com/enzdor/gomsg/models"
+	"github.com/enzdor/gomsg/utils"
+	"github.com/enzdor/gomsg/sqlc"
+)
+
+// fileNameRegexp matches the names uploads are stored with, anything else
+// in the path of a file is not ours to serve.
+var fileNameRegexp = regexp.MustCompile(`^[0-9a-f]{32}s?\.[a-z0-9]+$`)
+
+func (h *Handler) ServeFile(w http.ResponseWriter, r *http.Request) {
+	name := filepath.Base(r.URL.Path)
+	if r.URL.Path != "/files/" + name || !fileNameRegexp.MatchString(name) {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusNotFound), http.StatusSeeOther)
+	    return
+	}
+
+	attachment, err := h.q.GetAttachment(context.Background(), sqlc.GetAttachmentParams{FileName: name, ThumbName: name})
+	if err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(utils.ErrorStatus(err)), http.StatusSeeOther)
+	    return
+	}
+
+	mimeType := attachment.MimeType
+	if name == attachment.ThumbName {
+	    mimeType = mime.TypeByExtension(filepath.Ext(name))
+	}
+
+	file, err := os.Open(filepath.Join(h.uploadDir(), name))
+	if err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusNotFound), http.StatusSeeOther)
+	    return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+	    http.Redirect(w, r, "/error/" + strconv.Itoa(http.StatusInternalServerError), http.StatusSeeOther)
+	    return
+	}
+
+	// the type was sniffed when the file was uploaded, browsers should
+	// not guess again and the file must not run anything if it is opened
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; img-src 'self'; media-src 'self'; sandbox")
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+// parseForm parses a post form, which is multipart when it carries a file.
+// The body is capped a bit above the largest file the board allows, bigger
+// files are turned away before they are read to the end.
+func (h *Handler) parseForm(w http.ResponseWriter, r *http.Request, board sqlc.Board) error {
+	r.Body = http.MaxBytesReader(w, r.Body, int64(board.MaxFileSize) + 1 << 20)
+
+	err := r.ParseMultipartForm(1 << 20)
+	if errors.Is(err, http.ErrNotMultipart) {
+	    return r.ParseForm()
+	}
+
+	return err
+}
+
+// upload is a file sent with a post form that passed validation.
+type upload struct {
+	header *multipart.FileHeader
+	mimeType string
+	sha256 string
+}
+
+// validateUpload checks the file sent with a parsed post form against the
+// limits of the board, the banned hashes and the files already posted on
+// the board. It returns nil if no file was sent. An error without a form
+// error is a failure on our side.
+func (h *Handler) validateUpload(r *http.Request, board sqlc.Board) (*upload, models.FormError, error) {
+	if r.MultipartForm == nil || len(r.MultipartForm.File["file"]) == 0 {
+	    return nil, models.FormError{Bool: false, Message: "", Field: "file"}, nil
+	}
+	header := r.MultipartForm.File["file"][0]
+
+	mimeType, error, err := utils.ValidateUpload(header, board)
+	if err != nil {
+	    return nil, error, err
+	}
+
+	sum, err := utils.HashUpload(header)
+	if err != nil {
+	    return nil, error, err
+	}
+
+	error, err = utils.ValidateUploadHash(h.q, board, sum, time.Now())
+	if err != nil {
+	    return nil, error, err
+	}
+
+	return &upload{header: header, mimeType: mimeType, sha256: sum}, error, nil
+}
+
+// formStatus picks the status of the error page for a form that could not
+// be parsed.
+func formStatus(err error) int {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+	    return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusBadRequest
+}
+
+// saveUpload stores a validated upload in the upload directory under a
+// random name, so neither the name nor the extension sent by the browser
+// ever reach the disk. Images get a thumbnail next to them, named after the
+// original with an s for small. The returned params still need the thread
+// and reply of the post.
+func (h *Handler) saveUpload(file *upload) (sqlc.CreateAttachmentParams, error) {
+	header, mimeType := file.header, file.mimeType
+	params := sqlc.CreateAttachmentParams{
+	    OriginalName: filepath.Base(header.Filename),
+	    MimeType: mimeType,
+	    Size: int32(header.Size),
+	    Sha256: file.sha256,
+	    Date: time.Now(),
+	}
+	if len(params.OriginalName) > 255 {
+	    params.OriginalName = params.OriginalName[len(params.OriginalName) - 255:]
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+	    return params, err
+	}
+	params.FileName = hex.EncodeToString(b) + utils.FileExtensions[mimeType]
+	if ext, ok := utils.ThumbnailExtensions[mimeType]; ok {
+	    params.ThumbName = hex.EncodeToString(b) + "s" + ext
+	}
+
+	src, err := header.Open()
+	if err != nil {
+	    return params, err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(h.uploadDir(), 0755); err != nil {
+	    return params, err
+	}
+
+	dst, err := os.OpenFile(filepath.Join(h.uploadDir(), params.FileName), os.O_WRONLY | os.O_CREATE | os.O_EXCL, 0644)
+	if err != nil {
+	    return params, err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+	    dst.Close()
+	    h.removeFiles([]string{params.FileName})
+	    return params, err
+	}
+	if err := dst.Close(); err != nil {
+	    h.removeFiles([]string{params.FileName})
+	    return params, err
+	}
+
+	if params.ThumbName != "" {
+	    if err := h.saveThumbnail(src, params); err != nil {
+		// the image is still posted, it is only shown full size
+		log.Print(err)
+		params.ThumbName = ""
+	    }
+	}
+
+	return params, nil
+}
+
+func (h *Handler) saveThumbnail(src multipart.File, params sqlc.CreateAttachmentParams) error {
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+	    return err
+	}
+
+	dst, err := os.OpenFile(filepath.Join(h.uploadDir(), params.ThumbName), os.O_WRONLY | os.O_CREATE | os.O_EXCL, 0644)
+	if err != nil {
+	    return err
+	}
+
+	err = utils.EncodeThumbnail(dst, src, params.MimeType)
+	if cerr := dst.Close(); err == nil {
+	    err = cerr
+	}
+	if err != nil {
+	    h.removeFiles([]string{params.ThumbName})
+	}
+
+	return err
+}
+
+// createAttachment stores the attachment of a new post and adds its hash to
+// the upload history of the board, which duplicates are checked against.
+// The history is kept apart from the attachments, so pruning or deleting a
+// post does not let its file be posted again within the duplicate window.
+// Hashes the window has moved past are dropped on the way.
+//
+// The form already refused duplicates, but two posts of the same file sent
+// at once both pass that check, so it is made again here with the thread or
+// board row locked by the caller.
+func createAttachment(q *sqlc.Queries, board sqlc.Board, params sqlc.CreateAttachmentParams) error {
+	if board.DuplicateWindow > 0 {
+	    n, err := q.CountBoardDuplicates(context.Background(), sqlc.CountBoardDuplicatesParams{
+		BoardID: board.BoardID,
+		Sha256: params.Sha256,
+		Date: params.Date.Add(-time.Duration(board.DuplicateWindow) * time.Minute),
+	    })
+	    if err != nil {
+		return err
+	    }
+	    if n > 0 {
+		return &models.StatusError{Status: http.StatusBadRequest, Message: "This file has already been posted on this board"}
+	    }
+	}
+
+	if _, err := q.CreateAttachment(context.Background(), params); err != nil {
+	    return err
+	}
+
+	if _, err := q.CreateUploadHash(context.Background(), sqlc.CreateUploadHashParams{
+	    BoardID: board.BoardID,
+	    Sha256: params.Sha256,
+	    Date: params.Date,
+	}); err != nil {
+	    return err
+	}
+
+	_, err := q.DeleteStaleUploadHashes(context.Background(), sqlc.DeleteStaleUploadHashesParams{
+	    BoardID: board.BoardID,
+	    Date: params.Date.Add(-time.Duration(board.DuplicateWindow) * time.Minute),
+	})
+	return err
+}
+
+// uploadFiles lists the files stored on the disk for an attachment.
+func uploadFiles(fileName string, thumbName string) []string {
+	if thumbName == "" {
+	    return []string{fileName}
+	}
+
+	return []string{fileName, thumbName}
+}
+
+// removeFiles deletes uploads from the disk once their rows are gone. A
+// file that cannot be removed is only logged, the post it belonged to is
+// gone either way.
+func (h *Handler) removeFiles(names []string) {
+	for _, name := range names {
+	    if err := os.Remove(filepath.Join(h.uploadDir(), name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Print(err)
+	    }
+	}
+}
+
+// retireThread archives a thread that is leaving the board and drops its
+// attachments, archived threads keep their text but not their files. The
+// names of the files are returned so they can be removed once the
+// transaction has been committed.
+func retireThread(q *sqlc.Queries, threadID int32) ([]string, error) {
+	names, err := dropAttachments(q, threadID)
+	if err != nil {
+	    return nil, err
+	}
+
+	if _, err := q.ArchiveThread(context.Background(), threadID); err != nil {
+	    return nil, err
+	}
+
+	return names, nil
+}
+
+// dropAttachments deletes the attachments of a thread and its replies and
+// returns the names of their files.
+func dropAttachments(q *sqlc.Queries, threadID int32) ([]string, error) {
+	attachments, err := q.GetThreadAttachments(context.Background(), threadID)
+	if err != nil {
+	    return nil, err
+	}
+
+	if _, err := q.DeleteThreadAttachments(context.Background(), threadID); err != nil {
+	    return nil, err
+	}
+
+	return attachmentFiles(attachments), nil
+}
+
+// dropReplyAttachments deletes the attachments of a single reply and
+// returns the names of their files.
+func dropReplyAttachments(q *sqlc.Queries, replyID int32) ([]string, error) {
+	attachments, err := q.GetReplyAttachments(context.Background(), sql.NullInt32{Int32: replyID, Valid: true})
+	if err != nil {
+	    return nil, err
+	}
+
+	if _, err := q.DeleteReplyAttachments(context.Background(), sql.NullInt32{Int32: replyID, Valid: true}); err != nil {
+	    return nil, err
+	}
+
+	return attachmentFiles(attachments), nil
+}
+
+func attachmentFiles(attachments []sqlc.Attachment) []string {
+	names := []string{}
+	for _, attachment := range attachments {
+	    names = append(names, uploadFiles(attachment.FileName, attachment.ThumbName)...)
+	}
+
+	return names
+}
@@ -3,26 +3,165 @@ package controllers
 import (
 	"log"
 	"fmt"
+	"context"
+	"net/http"
+	"crypto/subtle"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
 	"database/sql"
 	"github.com/enzdor/gomsg/sqlc"
 )
 
+// DefaultPageSize is the number of threads on a page when the config does
+// not set one.
+const DefaultPageSize = 10
+
+// DefaultPreviews is the number of replies shown under each thread on the
+// board page when the config does not set one.
+const DefaultPreviews = 3
+
+// DefaultRecentCount is the number of threads and replies in the recent
+// activity feed on the index when the config does not set one.
+const DefaultRecentCount = 10
+
+// DefaultUploadDir is the directory uploads are stored in when the config
+// does not set one.
+const DefaultUploadDir = "uploads"
+
+type Config struct {
+	AdminUser string
+	AdminPass string
+	PageSize int32
+	Previews int32
+	RecentCount int32
+	UploadDir string
+	Secret string
+	RateLimitStore string
+}
+
 type Handler struct {
 	q *sqlc.Queries
 	db *sql.DB
+	cfg Config
+	limits limiter
 }
 
-func NewHandler(db *sql.DB) *Handler {
+func NewHandler(db *sql.DB, cfg Config) *Handler {
 	queries := sqlc.New(db)
 
-	return &Handler {
+	// without a configured secret poster IDs and CSRF tokens still work,
+	// but they change every time the server restarts, and instances
+	// sharing a database would not accept each other's tokens
+	if cfg.Secret == "" {
+	    if cfg.RateLimitStore == RateLimitDatabase {
+		log.Fatal("SECRET must be set when several instances share the database")
+	    }
+	    log.Print("WARNING: SECRET is not set, poster IDs and CSRF tokens will not survive a restart")
+
+	    b := make([]byte, 32)
+	    if _, err := rand.Read(b); err != nil {
+		log.Fatal(err)
+	    }
+	    cfg.Secret = hex.EncodeToString(b)
+	}
+
+	h := &Handler {
 		q: queries,
 		db: db,
+		cfg: cfg,
+	}
+
+	switch cfg.RateLimitStore {
+	case "", RateLimitMemory:
+	    h.limits = newMemoryLimiter()
+	case RateLimitDatabase:
+	    h.limits = &databaseLimiter{q: queries, withTx: h.withTx}
+	default:
+	    log.Fatalf("unknown rate limit store %q", cfg.RateLimitStore)
+	}
+
+	return h
+}
+
+func (h *Handler) pageSize() int32 {
+	if h.cfg.PageSize < 1 {
+	    return DefaultPageSize
+	}
+
+	return h.cfg.PageSize
+}
+
+func (h *Handler) previews() int32 {
+	if h.cfg.Previews < 1 {
+	    return DefaultPreviews
+	}
+
+	return h.cfg.Previews
+}
+
+func (h *Handler) recentCount() int32 {
+	if h.cfg.RecentCount < 1 {
+	    return DefaultRecentCount
+	}
+
+	return h.cfg.RecentCount
+}
+
+func (h *Handler) uploadDir() string {
+	if h.cfg.UploadDir == "" {
+	    return DefaultUploadDir
+	}
+
+	return h.cfg.UploadDir
+}
+
+// clientIP is the address of the client of a request, without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+	    return r.RemoteAddr
 	}
+
+	return host
+}
+
+// isAdmin checks the basic auth credentials of the request against the
+// configured admin account. An empty account disables the admin area.
+func (h *Handler) isAdmin(r *http.Request) bool {
+	if h.cfg.AdminUser == "" || h.cfg.AdminPass == "" {
+	    return false
+	}
+
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+	    return false
+	}
+
+	u := subtle.ConstantTimeCompare([]byte(user), []byte(h.cfg.AdminUser))
+	p := subtle.ConstantTimeCompare([]byte(pass), []byte(h.cfg.AdminPass))
+
+	return u & p == 1
+}
+
+// withTx runs fn with queries bound to a single transaction, which is
+// committed if fn succeeds and rolled back otherwise.
+func (h *Handler) withTx(ctx context.Context, fn func(q *sqlc.Queries) error) error {
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+	    return err
+	}
+
+	if err := fn(h.q.WithTx(tx)); err != nil {
+	    tx.Rollback()
+	    return err
+	}
+
+	return tx.Commit()
 }
 
 func NewDB(user string, pass string, name string) *sql.DB{
-	cfg := fmt.Sprintf("%s:%s@tcp(127.0.0.1:3306)/%s", user, pass, name)
+	cfg := fmt.Sprintf("%s:%s@tcp(127.0.0.1:3306)/%s?parseTime=true", user, pass, name)
 
 	db, err := sql.Open("mysql", cfg)
 	if err != nil {
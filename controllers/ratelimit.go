@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"log"
+	"sync"
+	"strconv"
+	"time"
+	"context"
+
+	"github.com/enzdor/gomsg/utils"
+	"github.com/enzdor/gomsg/sqlc"
+)
+
+// The stores the rate limits of posters can be kept in. The memory store
+// is the default, the database store shares the limits between several
+// instances of the server running against the same database.
+const (
+	RateLimitMemory = "memory"
+	RateLimitDatabase = "database"
+)
+
+// sweepInterval is how often the buckets that have refilled are dropped,
+// a full bucket is the same as no bucket.
+const sweepInterval = 10 * time.Minute
+
+// maxMemoryBuckets caps the buckets the memory store keeps between sweeps,
+// posters from many addresses at once would otherwise grow it without end.
+const maxMemoryBuckets = 100000
+
+// limiter keeps a token bucket for every key and reports whether a post
+// can take a token from it.
+type limiter interface {
+	allow(key string, burst int32, interval int32, now time.Time) (bool, error)
+}
+
+type bucket struct {
+	tokens float64
+	last time.Time
+	burst int32
+	interval int32
+}
+
+// full reports whether the bucket has refilled by now.
+func (b bucket) full(now time.Time) bool {
+	tokens, _ := utils.TakeToken(b.tokens, b.last, now, b.burst, b.interval)
+	return tokens + 1 >= float64(b.burst)
+}
+
+type memoryLimiter struct {
+	mu sync.Mutex
+	buckets map[string]bucket
+	swept time.Time
+}
+
+func newMemoryLimiter() *memoryLimiter {
+	return &memoryLimiter{buckets: map[string]bucket{}, swept: time.Now()}
+}
+
+func (l *memoryLimiter) allow(key string, burst int32, interval int32, now time.Time) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.swept) > sweepInterval {
+	    l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+	    if len(l.buckets) >= maxMemoryBuckets {
+		l.sweep(now)
+	    }
+	    if len(l.buckets) >= maxMemoryBuckets {
+		l.evict()
+	    }
+	    b = bucket{tokens: float64(burst), last: now}
+	}
+
+	tokens, allowed := utils.TakeToken(b.tokens, b.last, now, burst, interval)
+	l.buckets[key] = bucket{tokens: tokens, last: now, burst: burst, interval: interval}
+
+	return allowed, nil
+}
+
+// sweep drops the buckets that have refilled.
+func (l *memoryLimiter) sweep(now time.Time) {
+	for k, b := range l.buckets {
+	    if b.full(now) {
+		delete(l.buckets, k)
+	    }
+	}
+	l.swept = now
+}
+
+// evict makes room for a new bucket when none has refilled, by dropping the
+// one that was used the longest ago.
+func (l *memoryLimiter) evict() {
+	oldest := ""
+	var last time.Time
+	for k, b := range l.buckets {
+	    if oldest == "" || b.last.Before(last) {
+		oldest, last = k, b.last
+	    }
+	}
+	delete(l.buckets, oldest)
+}
+
+// databaseLimiter keeps the buckets in the rate_limits table. The row of a
+// bucket is locked while a token is taken, so posts sent to different
+// instances at once cannot both take the last one. The row is created and
+// locked by the same statement, a missing row only share locked by an
+// ignored insert would let two posts deadlock upgrading their locks.
+type databaseLimiter struct {
+	q *sqlc.Queries
+	withTx func(ctx context.Context, fn func(q *sqlc.Queries) error) error
+	mu sync.Mutex
+	swept time.Time
+}
+
+func (l *databaseLimiter) allow(key string, burst int32, interval int32, now time.Time) (bool, error) {
+	allowed := false
+
+	err := l.withTx(context.Background(), func(q *sqlc.Queries) error {
+	    if _, err := q.CreateRateLimit(context.Background(), sqlc.CreateRateLimitParams{
+		BucketKey: key,
+		Tokens: float64(burst),
+		UpdatedAt: now,
+	    }); err != nil {
+		return err
+	    }
+
+	    limit, err := q.GetRateLimitForUpdate(context.Background(), key)
+	    if err != nil {
+		return err
+	    }
+
+	    var tokens float64
+	    tokens, allowed = utils.TakeToken(limit.Tokens, limit.UpdatedAt, now, burst, interval)
+
+	    _, err = q.UpdateRateLimit(context.Background(), sqlc.UpdateRateLimitParams{
+		Tokens: tokens,
+		UpdatedAt: now,
+		BucketKey: key,
+	    })
+	    return err
+	})
+	if err != nil {
+	    return false, err
+	}
+
+	l.sweep(now)
+
+	return allowed, nil
+}
+
+// sweep deletes the rows no board could still be limiting a poster with,
+// every bucket has refilled after the longest burst at the longest
+// interval.
+func (l *databaseLimiter) sweep(now time.Time) {
+	l.mu.Lock()
+	if now.Sub(l.swept) < sweepInterval {
+	    l.mu.Unlock()
+	    return
+	}
+	l.swept = now
+	l.mu.Unlock()
+
+	stale := now.Add(-time.Duration(utils.MaxRateBurst * utils.MaxRateInterval) * time.Second)
+	if _, err := l.q.DeleteStaleRateLimits(context.Background(), stale); err != nil {
+	    log.Print(err)
+	}
+}
+
+// allowPost reports whether a poster can make one more post of kind on a
+// board. A limit that is turned off never reaches the store, so it neither
+// takes a lock nor keeps a bucket.
+func (h *Handler) allowPost(kind string, board sqlc.Board, ip string, burst int32, interval int32) (bool, error) {
+	if burst <= 0 || interval <= 0 {
+	    return true, nil
+	}
+
+	return h.limits.allow(rateKey(kind, board, ip), burst, interval, time.Now())
+}
+
+// rateKey names the bucket of a poster for one kind of post on a board. The
+// ID is used rather than the name, a board created again under an old name
+// does not inherit its buckets.
+func rateKey(kind string, board sqlc.Board, ip string) string {
+	return kind + ":" + strconv.Itoa(int(board.BoardID)) + ":" + ip
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 
 	"github.com/enzdor/gomsg/controllers"
 
@@ -24,17 +25,34 @@ func main() {
 	user := os.Getenv("DBUSER")
 	pass := os.Getenv("DBPASS")
 	name := os.Getenv("DBNAME")
+	pageSize, _ := strconv.Atoi(os.Getenv("PAGESIZE"))
+	previews, _ := strconv.Atoi(os.Getenv("PREVIEWS"))
+	recentCount, _ := strconv.Atoi(os.Getenv("RECENTCOUNT"))
+	cfg := controllers.Config{
+	    AdminUser: os.Getenv("ADMINUSER"),
+	    AdminPass: os.Getenv("ADMINPASS"),
+	    PageSize: int32(pageSize),
+	    Previews: int32(previews),
+	    RecentCount: int32(recentCount),
+	    UploadDir: os.Getenv("UPLOADDIR"),
+	    Secret: os.Getenv("SECRET"),
+	    RateLimitStore: os.Getenv("RATELIMITSTORE"),
+	}
 
 	db := controllers.NewDB(user, pass, name)
-	h := controllers.NewHandler(db)
+	h := controllers.NewHandler(db, cfg)
 
 	http.HandleFunc("/", h.ServeIndex)
 	http.HandleFunc("/board/", h.ServeBoard)
 	http.HandleFunc("/thread/", h.ServeThread)
+	http.HandleFunc("/archive/", h.ServeArchive)
 	http.HandleFunc("/post/", h.ServePost)
 	http.HandleFunc("/reply/", h.ServeReply)
+	http.HandleFunc("/delete/", h.ServeDelete)
 	http.HandleFunc("/kill/", h.ServeKill)
+	http.HandleFunc("/files/", h.ServeFile)
 	http.HandleFunc("/error/", h.ServeError)
+	http.HandleFunc("/admin/", h.ServeAdmin)
 	
 	log.Print("Listening on port :3000")
 	err := http.ListenAndServe(":3000", nil)
@@ -2,12 +2,14 @@ package models
 
 import (
     "net/http"
+    "time"
     "github.com/enzdor/gomsg/sqlc"
 )
 
 type ErrorData struct {
 	Status int
 	Message string
+	Boards []sqlc.Board
 }
 
 var (
@@ -15,33 +17,124 @@ var (
     InternalServerErrorData = ErrorData{Status: http.StatusInternalServerError, Message: "Internal server error"}
 )
 
+// Page is the position of a paginated listing, Previous and Next are 0 when
+// there is no page before or after it.
+type Page struct {
+	Number int
+	Previous int
+	Next int
+}
+
+// RecentPost is an entry of the recent activity feed, either a new thread or
+// a reply to one, in which case ReplyID is set.
+type RecentPost struct {
+	ThreadID int32
+	ReplyID int32
+	Title string
+	Comment string
+	Date time.Time
+	BoardName string
+}
+
 type IndexData struct {
-	Threads []sqlc.Thread
+	Posts []RecentPost
+	Page Page
+	Boards []sqlc.Board
 }
 
+// ThreadPreview is a thread on the board page with its last few replies,
+// Omitted counts the replies that are only shown on the thread page.
+type ThreadPreview struct {
+	sqlc.Thread
+	Attachments []sqlc.Attachment
+	Quotes []sqlc.Quote
+	Replies []sqlc.GetBoardPreviewsRow
+	Omitted int64
+}
+
+// BoardData holds the attachments and quotes of the previewed replies keyed
+// by reply id, the ones of each opening post are in its ThreadPreview.
 type BoardData struct {
+	Threads []ThreadPreview
+	Attachments map[int32][]sqlc.Attachment
+	Quotes map[int32][]sqlc.Quote
+	Board sqlc.Board
+	Page Page
+	Boards []sqlc.Board
+}
+
+type ArchiveData struct {
 	Threads []sqlc.Thread
-	Name string
+	Board sqlc.Board
+	Page Page
+	Boards []sqlc.Board
 }
 
+type CatalogData struct {
+	Threads []sqlc.GetBoardCatalogRow
+	Board sqlc.Board
+	Sort string
+	Sorts []string
+	Boards []sqlc.Board
+}
+
+// ThreadData holds the quotes made by each reply, keyed by the id of the
+// quoting reply, and the backlinks to each post, keyed by the id of the
+// quoted reply or in OpBacklinks for the opening post. Attachments are kept
+// the same way. PosterCounts counts the posts of each poster ID.
 type ThreadData struct {
 	Op sqlc.Thread
 	Replies []sqlc.Reply
+	Quotes map[int32][]sqlc.Quote
+	OpQuotes []sqlc.Quote
+	Backlinks map[int32][]sqlc.GetThreadBacklinksRow
+	OpBacklinks []sqlc.GetThreadBacklinksRow
+	Attachments map[int32][]sqlc.Attachment
+	OpAttachments []sqlc.Attachment
+	PosterCounts map[string]int
+	Board sqlc.Board
+	Archived bool
+	Boards []sqlc.Board
+	CSRFToken string
 }
 
 type PostData struct {
 	Title string
 	Comment string
 	Board string
+	MaxComment int32
 	Errors [2]FormError
+	FileError FormError
+	AllowedTypes string
+	Name string
+	ForceAnon bool
+	Boards []sqlc.Board
+	CSRFToken string
 }
 
 type ReplyData struct {
 	Comment string
 	Thread_id int
+	MaxComment int32
+	Sage bool
 	Error FormError
+	FileError FormError
+	AllowedTypes string
+	Name string
+	ForceAnon bool
+	Boards []sqlc.Board
+	CSRFToken string
 }
 
 type KillData struct {
 	Thread_id int
+	Boards []sqlc.Board
+}
+
+type AdminData struct {
+	Name string
+	Error FormError
+	Boards []sqlc.Board
+	BannedHashes []sqlc.BannedHash
+	CSRFToken string
 }
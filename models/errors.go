@@ -21,3 +21,12 @@ type PathError struct{
 func (e *PathError) Error() string{
     return e.Message
 }
+
+type StatusError struct{
+    Status int
+    Message string
+}
+
+func (e *StatusError) Error() string{
+    return e.Message
+}
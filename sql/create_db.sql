@@ -2,15 +2,38 @@ CREATE DATABASE gomsg;
 
 CREATE TABLE IF NOT EXISTS boards(
 	board_id INT AUTO_INCREMENT PRIMARY KEY,
-	name VARCHAR(100) NOT NULL
+	name VARCHAR(100) NOT NULL UNIQUE,
+	archived BOOLEAN NOT NULL DEFAULT FALSE,
+	max_threads INT NOT NULL DEFAULT 20,
+	max_replies INT NOT NULL DEFAULT 20,
+	bump_limit INT NOT NULL DEFAULT 15,
+	max_comment INT NOT NULL DEFAULT 1200,
+	links_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+	allowed_domains VARCHAR(1000) NOT NULL DEFAULT '',
+	denied_domains VARCHAR(1000) NOT NULL DEFAULT '',
+	max_file_size INT NOT NULL DEFAULT 2097152,
+	allowed_types VARCHAR(1000) NOT NULL DEFAULT 'image/jpeg, image/png, image/gif, image/webp',
+	duplicate_window INT NOT NULL DEFAULT 1440,
+	force_anon BOOLEAN NOT NULL DEFAULT FALSE,
+	poster_ids BOOLEAN NOT NULL DEFAULT FALSE,
+	thread_burst INT NOT NULL DEFAULT 3,
+	thread_interval INT NOT NULL DEFAULT 300,
+	reply_burst INT NOT NULL DEFAULT 5,
+	reply_interval INT NOT NULL DEFAULT 15
 );
 
 CREATE TABLE IF NOT EXISTS threads(
     thread_id INT AUTO_INCREMENT PRIMARY KEY,
     title VARCHAR(255) NOT NULL,
     comment VARCHAR(1275) NOT NULL,
-    date VARCHAR(15) NOT NULL,
+    date DATETIME NOT NULL,
+    last_bumped_at DATETIME NOT NULL,
     board_id INT NOT NULL,
+    status ENUM('alive', 'archived', 'deleted') NOT NULL DEFAULT 'alive',
+    delete_hash VARCHAR(255) NOT NULL DEFAULT '',
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    tripcode VARCHAR(20) NOT NULL DEFAULT '',
+    poster_id VARCHAR(16) NOT NULL DEFAULT '',
     CONSTRAINT fk_board
     FOREIGN KEY (board_id)
     REFERENCES boards(board_id)
@@ -21,7 +44,13 @@ CREATE TABLE IF NOT EXISTS threads(
 CREATE TABLE IF NOT EXISTS replies(
     reply_id INT AUTO_INCREMENT PRIMARY KEY,
     comment VARCHAR(1275) NOT NULL,
-    date VARCHAR(15) NOT NULL,
+    date DATETIME NOT NULL,
+    sage BOOLEAN NOT NULL DEFAULT FALSE,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    delete_hash VARCHAR(255) NOT NULL DEFAULT '',
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    tripcode VARCHAR(20) NOT NULL DEFAULT '',
+    poster_id VARCHAR(16) NOT NULL DEFAULT '',
     thread_id INT NOT NULL,
     CONSTRAINT fk_thread
     FOREIGN KEY (thread_id)
@@ -30,6 +59,83 @@ CREATE TABLE IF NOT EXISTS replies(
 	ON DELETE CASCADE
 );
 
+CREATE TABLE IF NOT EXISTS quotes(
+    quote_id INT AUTO_INCREMENT PRIMARY KEY,
+    thread_id INT NOT NULL,
+    reply_id INT,
+    quoted_thread_id INT NOT NULL,
+    quoted_reply_id INT,
+    quoted_board VARCHAR(255) NOT NULL DEFAULT '',
+    CONSTRAINT fk_quote_thread
+    FOREIGN KEY (thread_id)
+    REFERENCES threads(thread_id)
+	ON UPDATE CASCADE
+	ON DELETE CASCADE,
+    CONSTRAINT fk_quote_reply
+    FOREIGN KEY (reply_id)
+    REFERENCES replies(reply_id)
+	ON UPDATE CASCADE
+	ON DELETE CASCADE,
+    CONSTRAINT fk_quoted_thread
+    FOREIGN KEY (quoted_thread_id)
+    REFERENCES threads(thread_id)
+	ON UPDATE CASCADE
+	ON DELETE CASCADE,
+    CONSTRAINT fk_quoted_reply
+    FOREIGN KEY (quoted_reply_id)
+    REFERENCES replies(reply_id)
+	ON UPDATE CASCADE
+	ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS attachments(
+    attachment_id INT AUTO_INCREMENT PRIMARY KEY,
+    thread_id INT NOT NULL,
+    reply_id INT,
+    file_name VARCHAR(255) NOT NULL UNIQUE,
+    thumb_name VARCHAR(255) NOT NULL DEFAULT '',
+    original_name VARCHAR(255) NOT NULL,
+    mime_type VARCHAR(100) NOT NULL,
+    size INT NOT NULL,
+    sha256 CHAR(64) NOT NULL DEFAULT '',
+    date DATETIME NOT NULL,
+    CONSTRAINT fk_attachment_thread
+    FOREIGN KEY (thread_id)
+    REFERENCES threads(thread_id)
+	ON UPDATE CASCADE
+	ON DELETE CASCADE,
+    CONSTRAINT fk_attachment_reply
+    FOREIGN KEY (reply_id)
+    REFERENCES replies(reply_id)
+	ON UPDATE CASCADE
+	ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS banned_hashes(
+    sha256 CHAR(64) PRIMARY KEY,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    date DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS rate_limits(
+    bucket_key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE NOT NULL,
+    updated_at DATETIME(3) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS upload_hashes(
+    upload_hash_id INT AUTO_INCREMENT PRIMARY KEY,
+    board_id INT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    date DATETIME NOT NULL,
+    INDEX idx_upload_hash (board_id, sha256, date),
+    CONSTRAINT fk_upload_hash_board
+    FOREIGN KEY (board_id)
+    REFERENCES boards(board_id)
+	ON UPDATE CASCADE
+	ON DELETE CASCADE
+);
+
 
 
 
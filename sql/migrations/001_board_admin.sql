@@ -0,0 +1,3 @@
+ALTER TABLE boards
+	ADD CONSTRAINT uq_board_name UNIQUE (name),
+	ADD COLUMN archived BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+ALTER TABLE boards
+	ADD COLUMN max_threads INT NOT NULL DEFAULT 20,
+	ADD COLUMN max_replies INT NOT NULL DEFAULT 20,
+	ADD COLUMN max_comment INT NOT NULL DEFAULT 1200;
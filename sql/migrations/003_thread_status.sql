@@ -0,0 +1,2 @@
+ALTER TABLE threads
+	ADD COLUMN status ENUM('alive', 'archived', 'deleted') NOT NULL DEFAULT 'alive';
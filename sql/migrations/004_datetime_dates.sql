@@ -0,0 +1,23 @@
+-- Dates used to be stored as strings holding either Unix seconds, Unix
+-- milliseconds or 'YYYY/M/D', convert all of them to UTC DATETIME values.
+SET time_zone = '+00:00';
+
+ALTER TABLE threads ADD COLUMN date_new DATETIME NULL;
+UPDATE threads SET date_new = CASE
+	WHEN date REGEXP '^[0-9]{13}$' THEN FROM_UNIXTIME(date DIV 1000)
+	WHEN date REGEXP '^[0-9]{1,10}$' THEN FROM_UNIXTIME(date)
+	ELSE STR_TO_DATE(date, '%Y/%c/%e')
+END;
+ALTER TABLE threads
+	DROP COLUMN date,
+	CHANGE COLUMN date_new date DATETIME NOT NULL AFTER comment;
+
+ALTER TABLE replies ADD COLUMN date_new DATETIME NULL;
+UPDATE replies SET date_new = CASE
+	WHEN date REGEXP '^[0-9]{13}$' THEN FROM_UNIXTIME(date DIV 1000)
+	WHEN date REGEXP '^[0-9]{1,10}$' THEN FROM_UNIXTIME(date)
+	ELSE STR_TO_DATE(date, '%Y/%c/%e')
+END;
+ALTER TABLE replies
+	DROP COLUMN date,
+	CHANGE COLUMN date_new date DATETIME NOT NULL AFTER comment;
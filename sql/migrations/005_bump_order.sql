@@ -0,0 +1,11 @@
+ALTER TABLE boards
+	ADD COLUMN bump_limit INT NOT NULL DEFAULT 15 AFTER max_replies;
+
+ALTER TABLE threads
+	ADD COLUMN last_bumped_at DATETIME NULL AFTER date;
+UPDATE threads SET last_bumped_at = GREATEST(date, COALESCE(
+	(SELECT MAX(replies.date) FROM replies WHERE replies.thread_id = threads.thread_id),
+	date
+));
+ALTER TABLE threads
+	MODIFY COLUMN last_bumped_at DATETIME NOT NULL;
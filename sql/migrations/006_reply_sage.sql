@@ -0,0 +1,2 @@
+ALTER TABLE replies
+	ADD COLUMN sage BOOLEAN NOT NULL DEFAULT FALSE AFTER date;
@@ -0,0 +1,21 @@
+CREATE TABLE quotes(
+    quote_id INT AUTO_INCREMENT PRIMARY KEY,
+    reply_id INT NOT NULL,
+    quoted_thread_id INT NOT NULL,
+    quoted_reply_id INT,
+    CONSTRAINT fk_quote_reply
+    FOREIGN KEY (reply_id)
+    REFERENCES replies(reply_id)
+	ON UPDATE CASCADE
+	ON DELETE CASCADE,
+    CONSTRAINT fk_quoted_thread
+    FOREIGN KEY (quoted_thread_id)
+    REFERENCES threads(thread_id)
+	ON UPDATE CASCADE
+	ON DELETE CASCADE,
+    CONSTRAINT fk_quoted_reply
+    FOREIGN KEY (quoted_reply_id)
+    REFERENCES replies(reply_id)
+	ON UPDATE CASCADE
+	ON DELETE CASCADE
+);
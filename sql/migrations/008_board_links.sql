@@ -0,0 +1,4 @@
+ALTER TABLE boards
+	ADD COLUMN links_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+	ADD COLUMN allowed_domains VARCHAR(1000) NOT NULL DEFAULT '',
+	ADD COLUMN denied_domains VARCHAR(1000) NOT NULL DEFAULT '';
@@ -0,0 +1,23 @@
+ALTER TABLE boards
+	ADD COLUMN max_file_size INT NOT NULL DEFAULT 2097152,
+	ADD COLUMN allowed_types VARCHAR(1000) NOT NULL DEFAULT 'image/jpeg, image/png, image/gif, image/webp';
+
+CREATE TABLE attachments(
+    attachment_id INT AUTO_INCREMENT PRIMARY KEY,
+    thread_id INT NOT NULL,
+    reply_id INT,
+    file_name VARCHAR(255) NOT NULL UNIQUE,
+    original_name VARCHAR(255) NOT NULL,
+    mime_type VARCHAR(100) NOT NULL,
+    size INT NOT NULL,
+    CONSTRAINT fk_attachment_thread
+    FOREIGN KEY (thread_id)
+    REFERENCES threads(thread_id)
+	ON UPDATE CASCADE
+	ON DELETE CASCADE,
+    CONSTRAINT fk_attachment_reply
+    FOREIGN KEY (reply_id)
+    REFERENCES replies(reply_id)
+	ON UPDATE CASCADE
+	ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+ALTER TABLE attachments
+	ADD COLUMN thumb_name VARCHAR(255) NOT NULL DEFAULT '' AFTER file_name;
@@ -0,0 +1,18 @@
+ALTER TABLE boards
+	ADD COLUMN duplicate_window INT NOT NULL DEFAULT 1440;
+
+ALTER TABLE attachments
+	ADD COLUMN sha256 CHAR(64) NOT NULL DEFAULT '',
+	ADD COLUMN date DATETIME NULL;
+UPDATE attachments SET date = COALESCE(
+	(SELECT replies.date FROM replies WHERE replies.reply_id = attachments.reply_id),
+	(SELECT threads.date FROM threads WHERE threads.thread_id = attachments.thread_id)
+);
+ALTER TABLE attachments
+	MODIFY COLUMN date DATETIME NOT NULL;
+
+CREATE TABLE banned_hashes(
+    sha256 CHAR(64) PRIMARY KEY,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    date DATETIME NOT NULL
+);
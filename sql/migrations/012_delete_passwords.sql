@@ -0,0 +1,6 @@
+ALTER TABLE threads
+	ADD COLUMN delete_hash VARCHAR(255) NOT NULL DEFAULT '' AFTER status;
+
+ALTER TABLE replies
+	ADD COLUMN deleted BOOLEAN NOT NULL DEFAULT FALSE AFTER sage,
+	ADD COLUMN delete_hash VARCHAR(255) NOT NULL DEFAULT '' AFTER deleted;
@@ -0,0 +1,10 @@
+ALTER TABLE boards
+	ADD COLUMN force_anon BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE threads
+	ADD COLUMN name VARCHAR(100) NOT NULL DEFAULT '' AFTER delete_hash,
+	ADD COLUMN tripcode VARCHAR(20) NOT NULL DEFAULT '' AFTER name;
+
+ALTER TABLE replies
+	ADD COLUMN name VARCHAR(100) NOT NULL DEFAULT '' AFTER delete_hash,
+	ADD COLUMN tripcode VARCHAR(20) NOT NULL DEFAULT '' AFTER name;
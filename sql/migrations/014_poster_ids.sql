@@ -0,0 +1,8 @@
+ALTER TABLE boards
+	ADD COLUMN poster_ids BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE threads
+	ADD COLUMN poster_id VARCHAR(16) NOT NULL DEFAULT '' AFTER tripcode;
+
+ALTER TABLE replies
+	ADD COLUMN poster_id VARCHAR(16) NOT NULL DEFAULT '' AFTER tripcode;
@@ -0,0 +1,11 @@
+ALTER TABLE boards
+	ADD COLUMN thread_burst INT NOT NULL DEFAULT 3,
+	ADD COLUMN thread_interval INT NOT NULL DEFAULT 300,
+	ADD COLUMN reply_burst INT NOT NULL DEFAULT 5,
+	ADD COLUMN reply_interval INT NOT NULL DEFAULT 15;
+
+CREATE TABLE rate_limits(
+    bucket_key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE NOT NULL,
+    updated_at DATETIME(3) NOT NULL
+);
@@ -0,0 +1,13 @@
+ALTER TABLE quotes
+	ADD COLUMN thread_id INT NULL AFTER quote_id,
+	MODIFY COLUMN reply_id INT NULL;
+UPDATE quotes SET thread_id = (
+	SELECT replies.thread_id FROM replies WHERE replies.reply_id = quotes.reply_id
+);
+ALTER TABLE quotes
+	MODIFY COLUMN thread_id INT NOT NULL,
+	ADD CONSTRAINT fk_quote_thread
+	FOREIGN KEY (thread_id)
+	REFERENCES threads(thread_id)
+	ON UPDATE CASCADE
+	ON DELETE CASCADE;
@@ -0,0 +1,16 @@
+CREATE TABLE upload_hashes(
+    upload_hash_id INT AUTO_INCREMENT PRIMARY KEY,
+    board_id INT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    date DATETIME NOT NULL,
+    INDEX idx_upload_hash (board_id, sha256, date),
+    CONSTRAINT fk_upload_hash_board
+    FOREIGN KEY (board_id)
+    REFERENCES boards(board_id)
+	ON UPDATE CASCADE
+	ON DELETE CASCADE
+);
+INSERT INTO upload_hashes(board_id, sha256, date)
+SELECT threads.board_id, attachments.sha256, attachments.date FROM attachments
+JOIN threads ON threads.thread_id = attachments.thread_id
+WHERE attachments.sha256 <> '';
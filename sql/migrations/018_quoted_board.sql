@@ -0,0 +1,7 @@
+ALTER TABLE quotes
+	ADD COLUMN quoted_board VARCHAR(255) NOT NULL DEFAULT '' AFTER quoted_reply_id;
+UPDATE quotes SET quoted_board = (
+	SELECT boards.name FROM threads
+	JOIN boards ON boards.board_id = threads.board_id
+	WHERE threads.thread_id = quotes.quoted_thread_id
+) WHERE quoted_reply_id IS NULL;
@@ -1,40 +1,40 @@
-insert into threads (thread_id, title, comment, date, board_id) values (1, 'accumsan odio curabitur convallis duis consequat dui nec nisi volutpat', 'In hac habitasse platea dictumst. Morbi vestibulum, velit id pretium iaculis, diam erat fermentum justo, nec condimentum neque sapien placerat ante. Nulla justo.', '1663130913000', 1);
-insert into threads (thread_id, title, comment, date, board_id) values (2, 'ac nulla sed vel enim sit amet nunc viverra', 'Fusce consequat. Nulla nisl. Nunc nisl.', '1639670554000', 2);
-insert into threads (thread_id, title, comment, date, board_id) values (3, 'libero rutrum ac lobortis vel dapibus at diam nam', 'Quisque id justo sit amet sapien dignissim vestibulum. Vestibulum ante ipsum primis in faucibus orci luctus et ultrices posuere cubilia Curae; Nulla dapibus dolor vel est. Donec odio justo, sollicitudin ut, suscipit a, feugiat et, eros.', '1639399625000', 3);
-insert into threads (thread_id, title, comment, date, board_id) values (4, 'placerat ante nulla justo aliquam quis turpis eget elit', 'Proin eu mi. Nulla ac enim. In tempor, turpis nec euismod scelerisque, quam turpis adipiscing lorem, vitae mattis nibh ligula nec sem.', '1643690919000', 1);
-insert into threads (thread_id, title, comment, date, board_id) values (5, 'quam pede lobortis ligula sit amet eleifend pede libero', 'Vestibulum ac est lacinia nisi venenatis tristique. Fusce congue, diam id ornare imperdiet, sapien urna pretium nisl, ut volutpat sapien arcu sed augue. Aliquam erat volutpat.', '1660557198000', 2);
-insert into threads (thread_id, title, comment, date, board_id) values (6, 'amet cursus id turpis', 'Phasellus in felis. Donec semper sapien a libero. Nam dui.', '1667771653000', 3);
-insert into threads (thread_id, title, comment, date, board_id) values (7, 'eros suspendisse accumsan tortor quis turpis sed ante vivamus', 'Fusce posuere felis sed lacus. Morbi sem mauris, laoreet ut, rhoncus aliquet, pulvinar sed, nisl. Nunc rhoncus dui vel sem.', '1656871915000', 1);
-insert into threads (thread_id, title, comment, date, board_id) values (8, 'vitae consectetuer eget rutrum at lorem integer tincidunt ante vel', 'Integer tincidunt ante vel ipsum. Praesent blandit lacinia erat. Vestibulum sed magna at nunc commodo placerat.', '1641718926000', 2);
-insert into threads (thread_id, title, comment, date, board_id) values (9, 'nunc nisl duis bibendum felis sed interdum venenatis', 'Duis aliquam convallis nunc. Proin at turpis a pede posuere nonummy. Integer non velit.', '1660591004000', 3);
-insert into threads (thread_id, title, comment, date, board_id) values (10, 'volutpat erat quisque erat eros viverra', 'Proin interdum mauris non ligula pellentesque ultrices. Phasellus id sapien in sapien iaculis congue. Vivamus metus arcu, adipiscing molestie, hendrerit at, vulputate vitae, nisl.', '1668583328000', 1);
-insert into threads (thread_id, title, comment, date, board_id) values (11, 'in faucibus orci luctus', 'Sed ante. Vivamus tortor. Duis mattis egestas metus.', '1660598907000', 2);
-insert into threads (thread_id, title, comment, date, board_id) values (12, 'velit id pretium iaculis diam', 'Cras mi pede, malesuada in, imperdiet et, commodo vulputate, justo. In blandit ultrices enim. Lorem ipsum dolor sit amet, consectetuer adipiscing elit.', '1662601029000', 3);
-insert into threads (thread_id, title, comment, date, board_id) values (13, 'congue elementum in hac habitasse', 'Cras mi pede, malesuada in, imperdiet et, commodo vulputate, justo. In blandit ultrices enim. Lorem ipsum dolor sit amet, consectetuer adipiscing elit.', '1642399569000', 1);
-insert into threads (thread_id, title, comment, date, board_id) values (14, 'morbi sem mauris laoreet ut rhoncus aliquet pulvinar', 'Etiam vel augue. Vestibulum rutrum rutrum neque. Aenean auctor gravida sem.', '1647084239000', 2);
-insert into threads (thread_id, title, comment, date, board_id) values (15, 'sed ante vivamus tortor duis mattis egestas metus', 'Maecenas leo odio, condimentum id, luctus nec, molestie sed, justo. Pellentesque viverra pede ac diam. Cras pellentesque volutpat dui.', '1649206081000', 3);
-insert into threads (thread_id, title, comment, date, board_id) values (16, 'tortor eu pede', 'Nam ultrices, libero non mattis pulvinar, nulla pede ullamcorper augue, a suscipit nulla elit ac nulla. Sed vel enim sit amet nunc viverra dapibus. Nulla suscipit ligula in lacus.', '1668635675000', 1);
-insert into threads (thread_id, title, comment, date, board_id) values (17, 'in tempor turpis nec euismod', 'In congue. Etiam justo. Etiam pretium iaculis justo.', '1644663932000', 2);
-insert into threads (thread_id, title, comment, date, board_id) values (18, 'turpis nec euismod scelerisque quam turpis adipiscing', 'Maecenas tristique, est et tempus semper, est quam pharetra magna, ac consequat metus sapien ut nunc. Vestibulum ante ipsum primis in faucibus orci luctus et ultrices posuere cubilia Curae; Mauris viverra diam vitae quam. Suspendisse potenti.', '1660012773000', 3);
-insert into threads (thread_id, title, comment, date, board_id) values (19, 'neque sapien placerat ante nulla justo', 'In hac habitasse platea dictumst. Etiam faucibus cursus urna. Ut tellus.', '1663773511000', 1);
-insert into threads (thread_id, title, comment, date, board_id) values (20, 'non velit nec nisi vulputate nonummy maecenas tincidunt', 'Curabitur gravida nisi at nibh. In hac habitasse platea dictumst. Aliquam augue quam, sollicitudin vitae, consectetuer eget, rutrum at, lorem.', '1643486925000', 2);
-insert into threads (thread_id, title, comment, date, board_id) values (21, 'nulla ultrices aliquet maecenas leo odio condimentum', 'Aliquam quis turpis eget elit sodales scelerisque. Mauris sit amet eros. Suspendisse accumsan tortor quis turpis.', '1660332023000', 3);
-insert into threads (thread_id, title, comment, date, board_id) values (22, 'pede malesuada in imperdiet et commodo vulputate justo', 'Duis bibendum, felis sed interdum venenatis, turpis enim blandit mi, in porttitor pede justo eu massa. Donec dapibus. Duis at velit eu est congue elementum.', '1655919076000', 1);
-insert into threads (thread_id, title, comment, date, board_id) values (23, 'in faucibus orci luctus', 'Suspendisse potenti. In eleifend quam a odio. In hac habitasse platea dictumst.', '1641840649000', 2);
-insert into threads (thread_id, title, comment, date, board_id) values (24, 'suspendisse ornare consequat lectus in est risus auctor sed', 'In quis justo. Maecenas rhoncus aliquam lacus. Morbi quis tortor id nulla ultrices aliquet.', '1660999505000', 3);
-insert into threads (thread_id, title, comment, date, board_id) values (25, 'pede justo eu massa donec dapibus duis at velit eu', 'Pellentesque at nulla. Suspendisse potenti. Cras in purus eu magna vulputate luctus.', '1654705313000', 1);
-insert into threads (thread_id, title, comment, date, board_id) values (26, 'enim sit amet nunc', 'Curabitur in libero ut massa volutpat convallis. Morbi odio odio, elementum eu, interdum eu, tincidunt in, leo. Maecenas pulvinar lobortis est.', '1662981715000', 2);
-insert into threads (thread_id, title, comment, date, board_id) values (27, 'maecenas pulvinar lobortis est phasellus sit amet erat', 'Mauris enim leo, rhoncus sed, vestibulum sit amet, cursus id, turpis. Integer aliquet, massa id lobortis convallis, tortor risus dapibus augue, vel accumsan tellus nisi eu orci. Mauris lacinia sapien quis libero.', '1648107125000', 3);
-insert into threads (thread_id, title, comment, date, board_id) values (28, 'nec condimentum neque sapien placerat ante nulla justo aliquam quis', 'Nam ultrices, libero non mattis pulvinar, nulla pede ullamcorper augue, a suscipit nulla elit ac nulla. Sed vel enim sit amet nunc viverra dapibus. Nulla suscipit ligula in lacus.', '1644675575000', 1);
-insert into threads (thread_id, title, comment, date, board_id) values (29, 'ullamcorper purus sit amet nulla quisque arcu', 'Donec diam neque, vestibulum eget, vulputate ut, ultrices vel, augue. Vestibulum ante ipsum primis in faucibus orci luctus et ultrices posuere cubilia Curae; Donec pharetra, magna vestibulum aliquet ultrices, erat tortor sollicitudin mi, sit amet lobortis sapien sapien non mi. Integer ac neque.', '1651831629000', 2);
-insert into threads (thread_id, title, comment, date, board_id) values (30, 'tempus vivamus in felis eu', 'Duis bibendum, felis sed interdum venenatis, turpis enim blandit mi, in porttitor pede justo eu massa. Donec dapibus. Duis at velit eu est congue elementum.', '1643782446000', 3);
-insert into threads (thread_id, title, comment, date, board_id) values (31, 'sit amet eleifend pede libero', 'Morbi porttitor lorem id ligula. Suspendisse ornare consequat lectus. In est risus, auctor sed, tristique in, tempus sit amet, sem.', '1650805082000', 1);
-insert into threads (thread_id, title, comment, date, board_id) values (32, 'ipsum dolor sit', 'Pellentesque at nulla. Suspendisse potenti. Cras in purus eu magna vulputate luctus.', '1665436555000', 2);
-insert into threads (thread_id, title, comment, date, board_id) values (33, 'sapien dignissim vestibulum vestibulum', 'Cras mi pede, malesuada in, imperdiet et, commodo vulputate, justo. In blandit ultrices enim. Lorem ipsum dolor sit amet, consectetuer adipiscing elit.', '1652878882000', 3);
-insert into threads (thread_id, title, comment, date, board_id) values (34, 'elementum pellentesque quisque porta volutpat', 'Proin interdum mauris non ligula pellentesque ultrices. Phasellus id sapien in sapien iaculis congue. Vivamus metus arcu, adipiscing molestie, hendrerit at, vulputate vitae, nisl.', '1667225601000', 1);
-insert into threads (thread_id, title, comment, date, board_id) values (35, 'platea dictumst maecenas', 'Aenean fermentum. Donec ut mauris eget massa tempor convallis. Nulla neque libero, convallis eget, eleifend luctus, ultricies eu, nibh.', '1639150473000', 2);
-insert into threads (thread_id, title, comment, date, board_id) values (36, 'felis ut at dolor', 'In hac habitasse platea dictumst. Etiam faucibus cursus urna. Ut tellus.', '1660032855000', 3);
-insert into threads (thread_id, title, comment, date, board_id) values (37, 'sed justo pellentesque viverra pede ac', 'Duis consequat dui nec nisi volutpat eleifend. Donec ut dolor. Morbi vel lectus in quam fringilla rhoncus.', '1666333831000', 1);
-insert into threads (thread_id, title, comment, date, board_id) values (38, 'nibh fusce lacus purus aliquet at feugiat non pretium', 'Curabitur gravida nisi at nibh. In hac habitasse platea dictumst. Aliquam augue quam, sollicitudin vitae, consectetuer eget, rutrum at, lorem.', '1658449506000', 2);
-insert into threads (thread_id, title, comment, date, board_id) values (39, 'turpis enim blandit mi in porttitor pede justo', 'Maecenas leo odio, condimentum id, luctus nec, molestie sed, justo. Pellentesque viverra pede ac diam. Cras pellentesque volutpat dui.', '1644636062000', 3);
-insert into threads (thread_id, title, comment, date, board_id) values (40, 'justo sit amet sapien dignissim vestibulum vestibulum ante ipsum', 'Aenean lectus. Pellentesque eget nunc. Donec quis orci eget orci vehicula condimentum.', '1666005344000', 1);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (1, 'accumsan odio curabitur convallis duis consequat dui nec nisi volutpat', 'In hac habitasse platea dictumst. Morbi vestibulum, velit id pretium iaculis, diam erat fermentum justo, nec condimentum neque sapien placerat ante. Nulla justo.', '2022-09-14 04:48:33', '2022-11-20 00:00:00', 1);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (2, 'ac nulla sed vel enim sit amet nunc viverra', 'Fusce consequat. Nulla nisl. Nunc nisl.', '2021-12-16 16:02:34', '2022-12-01 00:00:00', 2);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (3, 'libero rutrum ac lobortis vel dapibus at diam nam', 'Quisque id justo sit amet sapien dignissim vestibulum. Vestibulum ante ipsum primis in faucibus orci luctus et ultrices posuere cubilia Curae; Nulla dapibus dolor vel est. Donec odio justo, sollicitudin ut, suscipit a, feugiat et, eros.', '2021-12-13 12:47:05', '2022-10-14 00:00:00', 3);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (4, 'placerat ante nulla justo aliquam quis turpis eget elit', 'Proin eu mi. Nulla ac enim. In tempor, turpis nec euismod scelerisque, quam turpis adipiscing lorem, vitae mattis nibh ligula nec sem.', '2022-02-01 04:48:39', '2022-09-14 00:00:00', 1);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (5, 'quam pede lobortis ligula sit amet eleifend pede libero', 'Vestibulum ac est lacinia nisi venenatis tristique. Fusce congue, diam id ornare imperdiet, sapien urna pretium nisl, ut volutpat sapien arcu sed augue. Aliquam erat volutpat.', '2022-08-15 09:53:18', '2022-10-12 00:00:00', 2);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (6, 'amet cursus id turpis', 'Phasellus in felis. Donec semper sapien a libero. Nam dui.', '2022-11-06 21:54:13', '2022-11-22 00:00:00', 3);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (7, 'eros suspendisse accumsan tortor quis turpis sed ante vivamus', 'Fusce posuere felis sed lacus. Morbi sem mauris, laoreet ut, rhoncus aliquet, pulvinar sed, nisl. Nunc rhoncus dui vel sem.', '2022-07-03 18:11:55', '2022-10-12 00:00:00', 1);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (8, 'vitae consectetuer eget rutrum at lorem integer tincidunt ante vel', 'Integer tincidunt ante vel ipsum. Praesent blandit lacinia erat. Vestibulum sed magna at nunc commodo placerat.', '2022-01-09 09:02:06', '2022-09-03 00:00:00', 2);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (9, 'nunc nisl duis bibendum felis sed interdum venenatis', 'Duis aliquam convallis nunc. Proin at turpis a pede posuere nonummy. Integer non velit.', '2022-08-15 19:16:44', '2022-08-15 19:16:44', 3);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (10, 'volutpat erat quisque erat eros viverra', 'Proin interdum mauris non ligula pellentesque ultrices. Phasellus id sapien in sapien iaculis congue. Vivamus metus arcu, adipiscing molestie, hendrerit at, vulputate vitae, nisl.', '2022-11-16 07:22:08', '2022-11-16 07:22:08', 1);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (11, 'in faucibus orci luctus', 'Sed ante. Vivamus tortor. Duis mattis egestas metus.', '2022-08-15 21:28:27', '2022-10-23 00:00:00', 2);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (12, 'velit id pretium iaculis diam', 'Cras mi pede, malesuada in, imperdiet et, commodo vulputate, justo. In blandit ultrices enim. Lorem ipsum dolor sit amet, consectetuer adipiscing elit.', '2022-09-08 01:37:09', '2022-10-24 00:00:00', 3);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (13, 'congue elementum in hac habitasse', 'Cras mi pede, malesuada in, imperdiet et, commodo vulputate, justo. In blandit ultrices enim. Lorem ipsum dolor sit amet, consectetuer adipiscing elit.', '2022-01-17 06:06:09', '2022-10-11 00:00:00', 1);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (14, 'morbi sem mauris laoreet ut rhoncus aliquet pulvinar', 'Etiam vel augue. Vestibulum rutrum rutrum neque. Aenean auctor gravida sem.', '2022-03-12 11:23:59', '2022-11-13 00:00:00', 2);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (15, 'sed ante vivamus tortor duis mattis egestas metus', 'Maecenas leo odio, condimentum id, luctus nec, molestie sed, justo. Pellentesque viverra pede ac diam. Cras pellentesque volutpat dui.', '2022-04-06 00:48:01', '2022-07-08 00:00:00', 3);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (16, 'tortor eu pede', 'Nam ultrices, libero non mattis pulvinar, nulla pede ullamcorper augue, a suscipit nulla elit ac nulla. Sed vel enim sit amet nunc viverra dapibus. Nulla suscipit ligula in lacus.', '2022-11-16 21:54:35', '2022-11-19 00:00:00', 1);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (17, 'in tempor turpis nec euismod', 'In congue. Etiam justo. Etiam pretium iaculis justo.', '2022-02-12 11:05:32', '2022-10-31 00:00:00', 2);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (18, 'turpis nec euismod scelerisque quam turpis adipiscing', 'Maecenas tristique, est et tempus semper, est quam pharetra magna, ac consequat metus sapien ut nunc. Vestibulum ante ipsum primis in faucibus orci luctus et ultrices posuere cubilia Curae; Mauris viverra diam vitae quam. Suspendisse potenti.', '2022-08-09 02:39:33', '2022-11-13 00:00:00', 3);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (19, 'neque sapien placerat ante nulla justo', 'In hac habitasse platea dictumst. Etiam faucibus cursus urna. Ut tellus.', '2022-09-21 15:18:31', '2022-12-03 00:00:00', 1);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (20, 'non velit nec nisi vulputate nonummy maecenas tincidunt', 'Curabitur gravida nisi at nibh. In hac habitasse platea dictumst. Aliquam augue quam, sollicitudin vitae, consectetuer eget, rutrum at, lorem.', '2022-01-29 20:08:45', '2022-11-17 00:00:00', 2);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (21, 'nulla ultrices aliquet maecenas leo odio condimentum', 'Aliquam quis turpis eget elit sodales scelerisque. Mauris sit amet eros. Suspendisse accumsan tortor quis turpis.', '2022-08-12 19:20:23', '2022-10-14 00:00:00', 3);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (22, 'pede malesuada in imperdiet et commodo vulputate justo', 'Duis bibendum, felis sed interdum venenatis, turpis enim blandit mi, in porttitor pede justo eu massa. Donec dapibus. Duis at velit eu est congue elementum.', '2022-06-22 17:31:16', '2022-11-22 00:00:00', 1);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (23, 'in faucibus orci luctus', 'Suspendisse potenti. In eleifend quam a odio. In hac habitasse platea dictumst.', '2022-01-10 18:50:49', '2022-10-24 00:00:00', 2);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (24, 'suspendisse ornare consequat lectus in est risus auctor sed', 'In quis justo. Maecenas rhoncus aliquam lacus. Morbi quis tortor id nulla ultrices aliquet.', '2022-08-20 12:45:05', '2022-11-26 00:00:00', 3);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (25, 'pede justo eu massa donec dapibus duis at velit eu', 'Pellentesque at nulla. Suspendisse potenti. Cras in purus eu magna vulputate luctus.', '2022-06-08 16:21:53', '2022-10-17 00:00:00', 1);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (26, 'enim sit amet nunc', 'Curabitur in libero ut massa volutpat convallis. Morbi odio odio, elementum eu, interdum eu, tincidunt in, leo. Maecenas pulvinar lobortis est.', '2022-09-12 11:21:55', '2022-09-17 00:00:00', 2);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (27, 'maecenas pulvinar lobortis est phasellus sit amet erat', 'Mauris enim leo, rhoncus sed, vestibulum sit amet, cursus id, turpis. Integer aliquet, massa id lobortis convallis, tortor risus dapibus augue, vel accumsan tellus nisi eu orci. Mauris lacinia sapien quis libero.', '2022-03-24 07:32:05', '2022-11-20 00:00:00', 3);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (28, 'nec condimentum neque sapien placerat ante nulla justo aliquam quis', 'Nam ultrices, libero non mattis pulvinar, nulla pede ullamcorper augue, a suscipit nulla elit ac nulla. Sed vel enim sit amet nunc viverra dapibus. Nulla suscipit ligula in lacus.', '2022-02-12 14:19:35', '2022-10-30 00:00:00', 1);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (29, 'ullamcorper purus sit amet nulla quisque arcu', 'Donec diam neque, vestibulum eget, vulputate ut, ultrices vel, augue. Vestibulum ante ipsum primis in faucibus orci luctus et ultrices posuere cubilia Curae; Donec pharetra, magna vestibulum aliquet ultrices, erat tortor sollicitudin mi, sit amet lobortis sapien sapien non mi. Integer ac neque.', '2022-05-06 10:07:09', '2022-11-18 00:00:00', 2);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (30, 'tempus vivamus in felis eu', 'Duis bibendum, felis sed interdum venenatis, turpis enim blandit mi, in porttitor pede justo eu massa. Donec dapibus. Duis at velit eu est congue elementum.', '2022-02-02 06:14:06', '2022-11-17 00:00:00', 3);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (31, 'sit amet eleifend pede libero', 'Morbi porttitor lorem id ligula. Suspendisse ornare consequat lectus. In est risus, auctor sed, tristique in, tempus sit amet, sem.', '2022-04-24 12:58:02', '2022-10-23 00:00:00', 1);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (32, 'ipsum dolor sit', 'Pellentesque at nulla. Suspendisse potenti. Cras in purus eu magna vulputate luctus.', '2022-10-10 21:15:55', '2022-11-11 00:00:00', 2);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (33, 'sapien dignissim vestibulum vestibulum', 'Cras mi pede, malesuada in, imperdiet et, commodo vulputate, justo. In blandit ultrices enim. Lorem ipsum dolor sit amet, consectetuer adipiscing elit.', '2022-05-18 13:01:22', '2022-11-12 00:00:00', 3);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (34, 'elementum pellentesque quisque porta volutpat', 'Proin interdum mauris non ligula pellentesque ultrices. Phasellus id sapien in sapien iaculis congue. Vivamus metus arcu, adipiscing molestie, hendrerit at, vulputate vitae, nisl.', '2022-10-31 14:13:21', '2022-11-12 00:00:00', 1);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (35, 'platea dictumst maecenas', 'Aenean fermentum. Donec ut mauris eget massa tempor convallis. Nulla neque libero, convallis eget, eleifend luctus, ultricies eu, nibh.', '2021-12-10 15:34:33', '2022-11-09 00:00:00', 2);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (36, 'felis ut at dolor', 'In hac habitasse platea dictumst. Etiam faucibus cursus urna. Ut tellus.', '2022-08-09 08:14:15', '2022-11-12 00:00:00', 3);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (37, 'sed justo pellentesque viverra pede ac', 'Duis consequat dui nec nisi volutpat eleifend. Donec ut dolor. Morbi vel lectus in quam fringilla rhoncus.', '2022-10-21 06:30:31', '2022-11-20 00:00:00', 1);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (38, 'nibh fusce lacus purus aliquet at feugiat non pretium', 'Curabitur gravida nisi at nibh. In hac habitasse platea dictumst. Aliquam augue quam, sollicitudin vitae, consectetuer eget, rutrum at, lorem.', '2022-07-22 00:25:06', '2022-09-05 00:00:00', 2);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (39, 'turpis enim blandit mi in porttitor pede justo', 'Maecenas leo odio, condimentum id, luctus nec, molestie sed, justo. Pellentesque viverra pede ac diam. Cras pellentesque volutpat dui.', '2022-02-12 03:21:02', '2022-11-25 00:00:00', 3);
+insert into threads (thread_id, title, comment, date, last_bumped_at, board_id) values (40, 'justo sit amet sapien dignissim vestibulum vestibulum ante ipsum', 'Aenean lectus. Pellentesque eget nunc. Donec quis orci eget orci vehicula condimentum.', '2022-10-17 11:15:44', '2022-11-11 00:00:00', 1);
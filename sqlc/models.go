@@ -4,24 +4,142 @@
 
 package sqlc
 
-import ()
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+type ThreadsStatus string
+
+const (
+	ThreadsStatusAlive    ThreadsStatus = "alive"
+	ThreadsStatusArchived ThreadsStatus = "archived"
+	ThreadsStatusDeleted  ThreadsStatus = "deleted"
+)
+
+func (e *ThreadsStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ThreadsStatus(s)
+	case string:
+		*e = ThreadsStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ThreadsStatus: %T", src)
+	}
+	return nil
+}
+
+type NullThreadsStatus struct {
+	ThreadsStatus ThreadsStatus
+	Valid         bool // Valid is true if ThreadsStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullThreadsStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ThreadsStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ThreadsStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullThreadsStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ThreadsStatus), nil
+}
+
+type Attachment struct {
+	AttachmentID int32
+	ThreadID     int32
+	ReplyID      sql.NullInt32
+	FileName     string
+	ThumbName    string
+	OriginalName string
+	MimeType     string
+	Size         int32
+	Sha256       string
+	Date         time.Time
+}
+
+type BannedHash struct {
+	Sha256 string
+	Reason string
+	Date   time.Time
+}
 
 type Board struct {
-	BoardID int32
-	Name    string
+	BoardID         int32
+	Name            string
+	Archived        bool
+	MaxThreads      int32
+	MaxReplies      int32
+	BumpLimit       int32
+	MaxComment      int32
+	LinksEnabled    bool
+	AllowedDomains  string
+	DeniedDomains   string
+	MaxFileSize     int32
+	AllowedTypes    string
+	DuplicateWindow int32
+	ForceAnon       bool
+	PosterIds       bool
+	ThreadBurst     int32
+	ThreadInterval  int32
+	ReplyBurst      int32
+	ReplyInterval   int32
+}
+
+type Quote struct {
+	QuoteID        int32
+	ThreadID       int32
+	ReplyID        sql.NullInt32
+	QuotedThreadID int32
+	QuotedReplyID  sql.NullInt32
+	QuotedBoard    string
+}
+
+type RateLimit struct {
+	BucketKey string
+	Tokens    float64
+	UpdatedAt time.Time
 }
 
 type Reply struct {
-	ReplyID  int32
-	Comment  string
-	Date     string
-	ThreadID int32
+	ReplyID    int32
+	Comment    string
+	Date       time.Time
+	Sage       bool
+	Deleted    bool
+	DeleteHash string
+	Name       string
+	Tripcode   string
+	PosterID   string
+	ThreadID   int32
 }
 
 type Thread struct {
-	ThreadID int32
-	Title    string
-	Comment  string
-	Date     string
-	BoardID  int32
+	ThreadID     int32
+	Title        string
+	Comment      string
+	Date         time.Time
+	LastBumpedAt time.Time
+	BoardID      int32
+	Status       ThreadsStatus
+	DeleteHash   string
+	Name         string
+	Tripcode     string
+	PosterID     string
+}
+
+type UploadHash struct {
+	UploadHashID int32
+	BoardID      int32
+	Sha256       string
+	Date         time.Time
 }
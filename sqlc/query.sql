@@ -1,48 +1,299 @@
+-- name: GetBoardByName :one
+SELECT * FROM boards
+WHERE name = ?
+LIMIT 1;
+
+-- name: GetBoard :one
+SELECT * FROM boards
+WHERE board_id = ?
+LIMIT 1;
+
+-- name: GetBoardForUpdate :one
+SELECT * FROM boards
+WHERE board_id = ?
+LIMIT 1
+FOR UPDATE;
+
+-- name: ListBoards :many
+SELECT * FROM boards
+ORDER BY board_id ASC;
+
+-- name: CreateBoard :execresult
+INSERT INTO boards(name)
+VALUES (?);
+
+-- name: RenameBoard :execresult
+UPDATE boards SET name = ?
+WHERE board_id = ?;
+
+-- name: SetBoardArchived :execresult
+UPDATE boards SET archived = ?
+WHERE board_id = ?;
+
+-- name: UpdateBoardLimits :execresult
+UPDATE boards SET max_threads = ?, max_replies = ?, bump_limit = ?, max_comment = ?
+WHERE board_id = ?;
+
+-- name: UpdateBoardLinks :execresult
+UPDATE boards SET links_enabled = ?, allowed_domains = ?, denied_domains = ?
+WHERE board_id = ?;
+
+-- name: UpdateBoardFiles :execresult
+UPDATE boards SET max_file_size = ?, allowed_types = ?, duplicate_window = ?
+WHERE board_id = ?;
+
+-- name: SetBoardForceAnon :execresult
+UPDATE boards SET force_anon = ?
+WHERE board_id = ?;
+
+-- name: SetBoardPosterIDs :execresult
+UPDATE boards SET poster_ids = ?
+WHERE board_id = ?;
+
+-- name: UpdateBoardRates :execresult
+UPDATE boards SET thread_burst = ?, thread_interval = ?, reply_burst = ?, reply_interval = ?
+WHERE board_id = ?;
+
+-- name: DeleteBoard :execresult
+DELETE FROM boards
+WHERE board_id = ?;
+
 -- name: GetBoardThreads :many
 SELECT * FROM threads
-WHERE board_id = ?
-ORDER BY date ASC;
+WHERE board_id = ? AND status = 'alive'
+ORDER BY last_bumped_at DESC, thread_id DESC
+LIMIT ? OFFSET ?;
 
--- name: GetThreads :many
+-- name: GetBoardArchivedThreads :many
 SELECT * FROM threads
-ORDER BY date ASC
-LIMIT ?;
+WHERE board_id = ? AND status = 'archived'
+ORDER BY date DESC, thread_id DESC
+LIMIT ? OFFSET ?;
 
 -- name: GetThread :one
 SELECT * FROM threads
 WHERE thread_id = ?
 LIMIT 1;
 
+-- name: GetThreadForUpdate :one
+SELECT * FROM threads
+WHERE thread_id = ?
+LIMIT 1
+FOR UPDATE;
+
 -- name: GetThreadReplies :many
 SELECT * FROM replies
 WHERE thread_id = ?
-ORDER BY date ASC;
+ORDER BY date ASC, reply_id ASC;
 
--- name: DeleteThread :execresult
-DELETE FROM threads
+-- name: ArchiveThread :execresult
+UPDATE threads SET status = 'archived'
+WHERE thread_id = ?;
+
+-- name: BumpThread :execresult
+UPDATE threads SET last_bumped_at = ?
 WHERE thread_id = ?;
 
 -- name: CreateThread :execresult
-INSERT INTO threads(title, comment, date, board_id)
-VALUES (?, ?, ?, ?);
+INSERT INTO threads(title, comment, date, last_bumped_at, board_id, delete_hash, name, tripcode)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: SetThreadPosterID :execresult
+UPDATE threads SET poster_id = ?
+WHERE thread_id = ?;
 
 -- name: CreateReply :execresult
-INSERT INTO replies(comment, date, thread_id)
-VALUES (?, ?, ?);
+INSERT INTO replies(comment, date, sage, thread_id, delete_hash, name, tripcode, poster_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: DeleteReply :execresult
+UPDATE replies SET deleted = TRUE, comment = ''
+WHERE reply_id = ?;
 
 -- name: CountReplies :one
 SELECT COUNT(*) FROM replies 
 WHERE thread_id = ?;
 
 -- name: CountThreads :one
-SELECT COUNT(*) FROM threads;
+SELECT COUNT(*) FROM threads
+WHERE status = 'alive';
+
+-- name: CountBoardThreads :one
+SELECT COUNT(*) FROM threads
+WHERE board_id = ? AND status = 'alive';
+
+-- name: CountBoardArchivedThreads :one
+SELECT COUNT(*) FROM threads
+WHERE board_id = ? AND status = 'archived';
 
 -- name: GetOldestThread :one
 SELECT * FROM threads 
-WHERE board_id = ?
-ORDER BY date ASC
+WHERE board_id = ? AND status = 'alive'
+ORDER BY last_bumped_at ASC, thread_id ASC
 LIMIT 1;
 
+-- name: GetBoardCatalog :many
+SELECT threads.*, COUNT(replies.reply_id) AS reply_count,
+	GREATEST(threads.date, COALESCE(MAX(replies.date), threads.date)) AS last_activity
+FROM threads
+LEFT JOIN replies ON replies.thread_id = threads.thread_id
+WHERE threads.board_id = ? AND threads.status = 'alive'
+GROUP BY threads.thread_id
+ORDER BY threads.last_bumped_at DESC, threads.thread_id DESC;
+
+-- name: GetBoardPreviews :many
+SELECT r.reply_id, r.comment, r.date, r.sage, r.deleted, r.name, r.tripcode, r.thread_id, r.reply_total FROM (
+	SELECT replies.*,
+		ROW_NUMBER() OVER (PARTITION BY replies.thread_id ORDER BY replies.date DESC, replies.reply_id DESC) AS reply_rank,
+		COUNT(*) OVER (PARTITION BY replies.thread_id) AS reply_total
+	FROM replies
+	JOIN (
+		SELECT thread_id FROM threads
+		WHERE board_id = ? AND status = 'alive'
+		ORDER BY last_bumped_at DESC, thread_id DESC
+		LIMIT ? OFFSET ?
+	) AS page ON page.thread_id = replies.thread_id
+) AS r
+WHERE r.reply_rank <= sqlc.arg(previews)
+ORDER BY r.thread_id, r.date ASC, r.reply_id ASC;
+
+-- name: GetRecentThreads :many
+SELECT threads.thread_id, threads.title, threads.comment, threads.date, boards.name AS board_name FROM threads
+JOIN boards ON boards.board_id = threads.board_id
+WHERE threads.status = 'alive'
+ORDER BY threads.date DESC, threads.thread_id DESC
+LIMIT ?;
+
+-- name: GetRecentReplies :many
+SELECT replies.reply_id, replies.comment, replies.date, threads.thread_id, threads.title, boards.name AS board_name FROM replies
+JOIN threads ON threads.thread_id = replies.thread_id
+JOIN boards ON boards.board_id = threads.board_id
+WHERE threads.status = 'alive' AND replies.deleted = FALSE
+ORDER BY replies.date DESC, replies.reply_id DESC
+LIMIT ?;
+
+-- name: CountAliveReplies :one
+SELECT COUNT(*) FROM replies
+JOIN threads ON threads.thread_id = replies.thread_id
+WHERE threads.status = 'alive' AND replies.deleted = FALSE;
+
+-- name: GetReply :one
+SELECT * FROM replies
+WHERE reply_id = ?
+LIMIT 1;
+
+-- name: CreateQuote :execresult
+INSERT INTO quotes(thread_id, reply_id, quoted_thread_id, quoted_reply_id, quoted_board)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: GetThreadQuotes :many
+SELECT * FROM quotes
+WHERE thread_id = ?;
+
+-- name: GetBoardQuotes :many
+SELECT quotes.* FROM quotes
+JOIN (
+	SELECT thread_id FROM threads
+	WHERE board_id = ? AND status = 'alive'
+	ORDER BY last_bumped_at DESC, thread_id DESC
+	LIMIT ? OFFSET ?
+) AS page ON page.thread_id = quotes.thread_id;
+
+-- name: GetThreadBacklinks :many
+SELECT quotes.reply_id, quotes.quoted_reply_id, quotes.thread_id, boards.name AS board_name FROM quotes
+JOIN threads ON threads.thread_id = quotes.thread_id
+JOIN boards ON boards.board_id = threads.board_id
+LEFT JOIN replies ON replies.reply_id = quotes.reply_id AND replies.deleted = FALSE
+WHERE quotes.quoted_thread_id = ? AND threads.status <> 'deleted'
+AND (quotes.reply_id IS NULL OR replies.reply_id IS NOT NULL)
+ORDER BY quotes.quote_id ASC;
+
+-- name: CreateAttachment :execresult
+INSERT INTO attachments(thread_id, reply_id, file_name, thumb_name, original_name, mime_type, size, sha256, date)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetThreadAttachments :many
+SELECT * FROM attachments
+WHERE thread_id = ?
+ORDER BY attachment_id ASC;
+
+-- name: GetAttachment :one
+SELECT * FROM attachments
+WHERE file_name = ? OR thumb_name = ?;
+
+-- name: GetBoardAttachments :many
+SELECT attachments.* FROM attachments
+JOIN (
+	SELECT thread_id FROM threads
+	WHERE board_id = ? AND status = 'alive'
+	ORDER BY last_bumped_at DESC, thread_id DESC
+	LIMIT ? OFFSET ?
+) AS page ON page.thread_id = attachments.thread_id
+ORDER BY attachments.attachment_id ASC;
+
+-- name: GetAllBoardAttachments :many
+SELECT attachments.* FROM attachments
+JOIN threads ON threads.thread_id = attachments.thread_id
+WHERE threads.board_id = ?;
+
+-- name: GetReplyAttachments :many
+SELECT * FROM attachments
+WHERE reply_id = ?
+ORDER BY attachment_id ASC;
+
+-- name: DeleteReplyAttachments :execresult
+DELETE FROM attachments
+WHERE reply_id = ?;
+
+-- name: DeleteThreadAttachments :execresult
+DELETE FROM attachments
+WHERE thread_id = ?;
+
+-- name: CountBoardDuplicates :one
+SELECT COUNT(*) FROM upload_hashes
+WHERE board_id = ? AND sha256 = ? AND date > ?;
+
+-- name: CreateUploadHash :execresult
+INSERT INTO upload_hashes(board_id, sha256, date)
+VALUES (?, ?, ?);
+
+-- name: DeleteStaleUploadHashes :execresult
+DELETE FROM upload_hashes
+WHERE board_id = ? AND date < ?;
+
+-- name: GetBannedHash :one
+SELECT * FROM banned_hashes
+WHERE sha256 = ?;
+
+-- name: ListBannedHashes :many
+SELECT * FROM banned_hashes
+ORDER BY date DESC;
+
+-- name: CreateBannedHash :execresult
+INSERT INTO banned_hashes(sha256, reason, date)
+VALUES (?, ?, ?);
+
+-- name: DeleteBannedHash :execresult
+DELETE FROM banned_hashes
+WHERE sha256 = ?;
+
+-- name: CreateRateLimit :execresult
+INSERT INTO rate_limits(bucket_key, tokens, updated_at)
+VALUES (?, ?, ?)
+ON DUPLICATE KEY UPDATE bucket_key = bucket_key;
+
+-- name: GetRateLimitForUpdate :one
+SELECT * FROM rate_limits
+WHERE bucket_key = ?
+FOR UPDATE;
+
+-- name: UpdateRateLimit :execresult
+UPDATE rate_limits SET tokens = ?, updated_at = ?
+WHERE bucket_key = ?;
+
+-- name: DeleteStaleRateLimits :execresult
+DELETE FROM rate_limits
+WHERE updated_at < ?;
 
 
 
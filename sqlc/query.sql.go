@@ -8,8 +8,87 @@ package sqlc
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
+const archiveThread = `-- name: ArchiveThread :execresult
+UPDATE threads SET status = 'archived'
+WHERE thread_id = ?
+`
+
+func (q *Queries) ArchiveThread(ctx context.Context, threadID int32) (sql.Result, error) {
+	return q.db.ExecContext(ctx, archiveThread, threadID)
+}
+
+const bumpThread = `-- name: BumpThread :execresult
+UPDATE threads SET last_bumped_at = ?
+WHERE thread_id = ?
+`
+
+type BumpThreadParams struct {
+	LastBumpedAt time.Time
+	ThreadID     int32
+}
+
+func (q *Queries) BumpThread(ctx context.Context, arg BumpThreadParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, bumpThread, arg.LastBumpedAt, arg.ThreadID)
+}
+
+const countAliveReplies = `-- name: CountAliveReplies :one
+SELECT COUNT(*) FROM replies
+JOIN threads ON threads.thread_id = replies.thread_id
+WHERE threads.status = 'alive' AND replies.deleted = FALSE
+`
+
+func (q *Queries) CountAliveReplies(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAliveReplies)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countBoardArchivedThreads = `-- name: CountBoardArchivedThreads :one
+SELECT COUNT(*) FROM threads
+WHERE board_id = ? AND status = 'archived'
+`
+
+func (q *Queries) CountBoardArchivedThreads(ctx context.Context, boardID int32) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countBoardArchivedThreads, boardID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countBoardDuplicates = `-- name: CountBoardDuplicates :one
+SELECT COUNT(*) FROM upload_hashes
+WHERE board_id = ? AND sha256 = ? AND date > ?
+`
+
+type CountBoardDuplicatesParams struct {
+	BoardID int32
+	Sha256  string
+	Date    time.Time
+}
+
+func (q *Queries) CountBoardDuplicates(ctx context.Context, arg CountBoardDuplicatesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countBoardDuplicates, arg.BoardID, arg.Sha256, arg.Date)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countBoardThreads = `-- name: CountBoardThreads :one
+SELECT COUNT(*) FROM threads
+WHERE board_id = ? AND status = 'alive'
+`
+
+func (q *Queries) CountBoardThreads(ctx context.Context, boardID int32) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countBoardThreads, boardID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countReplies = `-- name: CountReplies :one
 SELECT COUNT(*) FROM replies 
 WHERE thread_id = ?
@@ -24,6 +103,7 @@ func (q *Queries) CountReplies(ctx context.Context, threadID int32) (int64, erro
 
 const countThreads = `-- name: CountThreads :one
 SELECT COUNT(*) FROM threads
+WHERE status = 'alive'
 `
 
 func (q *Queries) CountThreads(ctx context.Context) (int64, error) {
@@ -33,31 +113,143 @@ func (q *Queries) CountThreads(ctx context.Context) (int64, error) {
 	return count, err
 }
 
-const createReply = `-- name: CreateReply :execresult
-INSERT INTO replies(comment, date, thread_id)
+const createAttachment = `-- name: CreateAttachment :execresult
+INSERT INTO attachments(thread_id, reply_id, file_name, thumb_name, original_name, mime_type, size, sha256, date)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+type CreateAttachmentParams struct {
+	ThreadID     int32
+	ReplyID      sql.NullInt32
+	FileName     string
+	ThumbName    string
+	OriginalName string
+	MimeType     string
+	Size         int32
+	Sha256       string
+	Date         time.Time
+}
+
+func (q *Queries) CreateAttachment(ctx context.Context, arg CreateAttachmentParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, createAttachment,
+		arg.ThreadID,
+		arg.ReplyID,
+		arg.FileName,
+		arg.ThumbName,
+		arg.OriginalName,
+		arg.MimeType,
+		arg.Size,
+		arg.Sha256,
+		arg.Date,
+	)
+}
+
+const createBannedHash = `-- name: CreateBannedHash :execresult
+INSERT INTO banned_hashes(sha256, reason, date)
+VALUES (?, ?, ?)
+`
+
+type CreateBannedHashParams struct {
+	Sha256 string
+	Reason string
+	Date   time.Time
+}
+
+func (q *Queries) CreateBannedHash(ctx context.Context, arg CreateBannedHashParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, createBannedHash, arg.Sha256, arg.Reason, arg.Date)
+}
+
+const createBoard = `-- name: CreateBoard :execresult
+INSERT INTO boards(name)
+VALUES (?)
+`
+
+func (q *Queries) CreateBoard(ctx context.Context, name string) (sql.Result, error) {
+	return q.db.ExecContext(ctx, createBoard, name)
+}
+
+const createQuote = `-- name: CreateQuote :execresult
+INSERT INTO quotes(thread_id, reply_id, quoted_thread_id, quoted_reply_id, quoted_board)
+VALUES (?, ?, ?, ?, ?)
+`
+
+type CreateQuoteParams struct {
+	ThreadID       int32
+	ReplyID        sql.NullInt32
+	QuotedThreadID int32
+	QuotedReplyID  sql.NullInt32
+	QuotedBoard    string
+}
+
+func (q *Queries) CreateQuote(ctx context.Context, arg CreateQuoteParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, createQuote,
+		arg.ThreadID,
+		arg.ReplyID,
+		arg.QuotedThreadID,
+		arg.QuotedReplyID,
+		arg.QuotedBoard,
+	)
+}
+
+const createRateLimit = `-- name: CreateRateLimit :execresult
+INSERT INTO rate_limits(bucket_key, tokens, updated_at)
 VALUES (?, ?, ?)
+ON DUPLICATE KEY UPDATE bucket_key = bucket_key
+`
+
+type CreateRateLimitParams struct {
+	BucketKey string
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+func (q *Queries) CreateRateLimit(ctx context.Context, arg CreateRateLimitParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, createRateLimit, arg.BucketKey, arg.Tokens, arg.UpdatedAt)
+}
+
+const createReply = `-- name: CreateReply :execresult
+INSERT INTO replies(comment, date, sage, thread_id, delete_hash, name, tripcode, poster_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 `
 
 type CreateReplyParams struct {
-	Comment  string
-	Date     string
-	ThreadID int32
+	Comment    string
+	Date       time.Time
+	Sage       bool
+	ThreadID   int32
+	DeleteHash string
+	Name       string
+	Tripcode   string
+	PosterID   string
 }
 
 func (q *Queries) CreateReply(ctx context.Context, arg CreateReplyParams) (sql.Result, error) {
-	return q.db.ExecContext(ctx, createReply, arg.Comment, arg.Date, arg.ThreadID)
+	return q.db.ExecContext(ctx, createReply,
+		arg.Comment,
+		arg.Date,
+		arg.Sage,
+		arg.ThreadID,
+		arg.DeleteHash,
+		arg.Name,
+		arg.Tripcode,
+		arg.PosterID,
+	)
 }
 
 const createThread = `-- name: CreateThread :execresult
-INSERT INTO threads(title, comment, date, board_id)
-VALUES (?, ?, ?, ?)
+INSERT INTO threads(title, comment, date, last_bumped_at, board_id, delete_hash, name, tripcode)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 `
 
 type CreateThreadParams struct {
-	Title   string
-	Comment string
-	Date    string
-	BoardID int32
+	Title        string
+	Comment      string
+	Date         time.Time
+	LastBumpedAt time.Time
+	BoardID      int32
+	DeleteHash   string
+	Name         string
+	Tripcode     string
 }
 
 func (q *Queries) CreateThread(ctx context.Context, arg CreateThreadParams) (sql.Result, error) {
@@ -65,40 +257,123 @@ func (q *Queries) CreateThread(ctx context.Context, arg CreateThreadParams) (sql
 		arg.Title,
 		arg.Comment,
 		arg.Date,
+		arg.LastBumpedAt,
 		arg.BoardID,
+		arg.DeleteHash,
+		arg.Name,
+		arg.Tripcode,
 	)
 }
 
-const deleteThread = `-- name: DeleteThread :execresult
-DELETE FROM threads
-WHERE thread_id = ?
+const createUploadHash = `-- name: CreateUploadHash :execresult
+INSERT INTO upload_hashes(board_id, sha256, date)
+VALUES (?, ?, ?)
 `
 
-func (q *Queries) DeleteThread(ctx context.Context, threadID int32) (sql.Result, error) {
-	return q.db.ExecContext(ctx, deleteThread, threadID)
+type CreateUploadHashParams struct {
+	BoardID int32
+	Sha256  string
+	Date    time.Time
 }
 
-const getBoardThreads = `-- name: GetBoardThreads :many
-SELECT thread_id, title, comment, date, board_id FROM threads
+func (q *Queries) CreateUploadHash(ctx context.Context, arg CreateUploadHashParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, createUploadHash, arg.BoardID, arg.Sha256, arg.Date)
+}
+
+const deleteBannedHash = `-- name: DeleteBannedHash :execresult
+DELETE FROM banned_hashes
+WHERE sha256 = ?
+`
+
+func (q *Queries) DeleteBannedHash(ctx context.Context, sha256 string) (sql.Result, error) {
+	return q.db.ExecContext(ctx, deleteBannedHash, sha256)
+}
+
+const deleteBoard = `-- name: DeleteBoard :execresult
+DELETE FROM boards
 WHERE board_id = ?
-ORDER BY date ASC
 `
 
-func (q *Queries) GetBoardThreads(ctx context.Context, boardID int32) ([]Thread, error) {
-	rows, err := q.db.QueryContext(ctx, getBoardThreads, boardID)
+func (q *Queries) DeleteBoard(ctx context.Context, boardID int32) (sql.Result, error) {
+	return q.db.ExecContext(ctx, deleteBoard, boardID)
+}
+
+const deleteReply = `-- name: DeleteReply :execresult
+UPDATE replies SET deleted = TRUE, comment = ''
+WHERE reply_id = ?
+`
+
+func (q *Queries) DeleteReply(ctx context.Context, replyID int32) (sql.Result, error) {
+	return q.db.ExecContext(ctx, deleteReply, replyID)
+}
+
+const deleteReplyAttachments = `-- name: DeleteReplyAttachments :execresult
+DELETE FROM attachments
+WHERE reply_id = ?
+`
+
+func (q *Queries) DeleteReplyAttachments(ctx context.Context, replyID sql.NullInt32) (sql.Result, error) {
+	return q.db.ExecContext(ctx, deleteReplyAttachments, replyID)
+}
+
+const deleteStaleRateLimits = `-- name: DeleteStaleRateLimits :execresult
+DELETE FROM rate_limits
+WHERE updated_at < ?
+`
+
+func (q *Queries) DeleteStaleRateLimits(ctx context.Context, updatedAt time.Time) (sql.Result, error) {
+	return q.db.ExecContext(ctx, deleteStaleRateLimits, updatedAt)
+}
+
+const deleteStaleUploadHashes = `-- name: DeleteStaleUploadHashes :execresult
+DELETE FROM upload_hashes
+WHERE board_id = ? AND date < ?
+`
+
+type DeleteStaleUploadHashesParams struct {
+	BoardID int32
+	Date    time.Time
+}
+
+func (q *Queries) DeleteStaleUploadHashes(ctx context.Context, arg DeleteStaleUploadHashesParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, deleteStaleUploadHashes, arg.BoardID, arg.Date)
+}
+
+const deleteThreadAttachments = `-- name: DeleteThreadAttachments :execresult
+DELETE FROM attachments
+WHERE thread_id = ?
+`
+
+func (q *Queries) DeleteThreadAttachments(ctx context.Context, threadID int32) (sql.Result, error) {
+	return q.db.ExecContext(ctx, deleteThreadAttachments, threadID)
+}
+
+const getAllBoardAttachments = `-- name: GetAllBoardAttachments :many
+SELECT attachments.attachment_id, attachments.thread_id, attachments.reply_id, attachments.file_name, attachments.thumb_name, attachments.original_name, attachments.mime_type, attachments.size, attachments.sha256, attachments.date FROM attachments
+JOIN threads ON threads.thread_id = attachments.thread_id
+WHERE threads.board_id = ?
+`
+
+func (q *Queries) GetAllBoardAttachments(ctx context.Context, boardID int32) ([]Attachment, error) {
+	rows, err := q.db.QueryContext(ctx, getAllBoardAttachments, boardID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Thread
+	var items []Attachment
 	for rows.Next() {
-		var i Thread
+		var i Attachment
 		if err := rows.Scan(
+			&i.AttachmentID,
 			&i.ThreadID,
-			&i.Title,
-			&i.Comment,
+			&i.ReplyID,
+			&i.FileName,
+			&i.ThumbName,
+			&i.OriginalName,
+			&i.MimeType,
+			&i.Size,
+			&i.Sha256,
 			&i.Date,
-			&i.BoardID,
 		); err != nil {
 			return nil, err
 		}
@@ -113,65 +388,164 @@ func (q *Queries) GetBoardThreads(ctx context.Context, boardID int32) ([]Thread,
 	return items, nil
 }
 
-const getOldestThread = `-- name: GetOldestThread :one
-SELECT thread_id, title, comment, date, board_id FROM threads 
-WHERE board_id = ?
-ORDER BY date ASC
-LIMIT 1
+const getAttachment = `-- name: GetAttachment :one
+SELECT attachment_id, thread_id, reply_id, file_name, thumb_name, original_name, mime_type, size, sha256, date FROM attachments
+WHERE file_name = ? OR thumb_name = ?
 `
 
-func (q *Queries) GetOldestThread(ctx context.Context, boardID int32) (Thread, error) {
-	row := q.db.QueryRowContext(ctx, getOldestThread, boardID)
-	var i Thread
+type GetAttachmentParams struct {
+	FileName  string
+	ThumbName string
+}
+
+func (q *Queries) GetAttachment(ctx context.Context, arg GetAttachmentParams) (Attachment, error) {
+	row := q.db.QueryRowContext(ctx, getAttachment, arg.FileName, arg.ThumbName)
+	var i Attachment
 	err := row.Scan(
+		&i.AttachmentID,
 		&i.ThreadID,
-		&i.Title,
-		&i.Comment,
+		&i.ReplyID,
+		&i.FileName,
+		&i.ThumbName,
+		&i.OriginalName,
+		&i.MimeType,
+		&i.Size,
+		&i.Sha256,
 		&i.Date,
-		&i.BoardID,
 	)
 	return i, err
 }
 
-const getThread = `-- name: GetThread :one
-SELECT thread_id, title, comment, date, board_id FROM threads
-WHERE thread_id = ?
+const getBannedHash = `-- name: GetBannedHash :one
+SELECT sha256, reason, date FROM banned_hashes
+WHERE sha256 = ?
+`
+
+func (q *Queries) GetBannedHash(ctx context.Context, sha256 string) (BannedHash, error) {
+	row := q.db.QueryRowContext(ctx, getBannedHash, sha256)
+	var i BannedHash
+	err := row.Scan(&i.Sha256, &i.Reason, &i.Date)
+	return i, err
+}
+
+const getBoard = `-- name: GetBoard :one
+SELECT board_id, name, archived, max_threads, max_replies, bump_limit, max_comment, links_enabled, allowed_domains, denied_domains, max_file_size, allowed_types, duplicate_window, force_anon, poster_ids, thread_burst, thread_interval, reply_burst, reply_interval FROM boards
+WHERE board_id = ?
 LIMIT 1
 `
 
-func (q *Queries) GetThread(ctx context.Context, threadID int32) (Thread, error) {
-	row := q.db.QueryRowContext(ctx, getThread, threadID)
-	var i Thread
+func (q *Queries) GetBoard(ctx context.Context, boardID int32) (Board, error) {
+	row := q.db.QueryRowContext(ctx, getBoard, boardID)
+	var i Board
 	err := row.Scan(
-		&i.ThreadID,
-		&i.Title,
-		&i.Comment,
-		&i.Date,
 		&i.BoardID,
+		&i.Name,
+		&i.Archived,
+		&i.MaxThreads,
+		&i.MaxReplies,
+		&i.BumpLimit,
+		&i.MaxComment,
+		&i.LinksEnabled,
+		&i.AllowedDomains,
+		&i.DeniedDomains,
+		&i.MaxFileSize,
+		&i.AllowedTypes,
+		&i.DuplicateWindow,
+		&i.ForceAnon,
+		&i.PosterIds,
+		&i.ThreadBurst,
+		&i.ThreadInterval,
+		&i.ReplyBurst,
+		&i.ReplyInterval,
 	)
 	return i, err
 }
 
-const getThreadReplies = `-- name: GetThreadReplies :many
-SELECT reply_id, comment, date, thread_id FROM replies
-WHERE thread_id = ?
-ORDER BY date ASC
+const getBoardArchivedThreads = `-- name: GetBoardArchivedThreads :many
+SELECT thread_id, title, comment, date, last_bumped_at, board_id, status, delete_hash, name, tripcode, poster_id FROM threads
+WHERE board_id = ? AND status = 'archived'
+ORDER BY date DESC, thread_id DESC
+LIMIT ? OFFSET ?
 `
 
-func (q *Queries) GetThreadReplies(ctx context.Context, threadID int32) ([]Reply, error) {
-	rows, err := q.db.QueryContext(ctx, getThreadReplies, threadID)
+type GetBoardArchivedThreadsParams struct {
+	BoardID int32
+	Limit   int32
+	Offset  int32
+}
+
+func (q *Queries) GetBoardArchivedThreads(ctx context.Context, arg GetBoardArchivedThreadsParams) ([]Thread, error) {
+	rows, err := q.db.QueryContext(ctx, getBoardArchivedThreads, arg.BoardID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Reply
+	var items []Thread
 	for rows.Next() {
-		var i Reply
+		var i Thread
 		if err := rows.Scan(
-			&i.ReplyID,
+			&i.ThreadID,
+			&i.Title,
 			&i.Comment,
 			&i.Date,
+			&i.LastBumpedAt,
+			&i.BoardID,
+			&i.Status,
+			&i.DeleteHash,
+			&i.Name,
+			&i.Tripcode,
+			&i.PosterID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBoardAttachments = `-- name: GetBoardAttachments :many
+SELECT attachments.attachment_id, attachments.thread_id, attachments.reply_id, attachments.file_name, attachments.thumb_name, attachments.original_name, attachments.mime_type, attachments.size, attachments.sha256, attachments.date FROM attachments
+JOIN (
+	SELECT thread_id FROM threads
+	WHERE board_id = ? AND status = 'alive'
+	ORDER BY last_bumped_at DESC, thread_id DESC
+	LIMIT ? OFFSET ?
+) AS page ON page.thread_id = attachments.thread_id
+ORDER BY attachments.attachment_id ASC
+`
+
+type GetBoardAttachmentsParams struct {
+	BoardID int32
+	Limit   int32
+	Offset  int32
+}
+
+func (q *Queries) GetBoardAttachments(ctx context.Context, arg GetBoardAttachmentsParams) ([]Attachment, error) {
+	rows, err := q.db.QueryContext(ctx, getBoardAttachments, arg.BoardID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Attachment
+	for rows.Next() {
+		var i Attachment
+		if err := rows.Scan(
+			&i.AttachmentID,
 			&i.ThreadID,
+			&i.ReplyID,
+			&i.FileName,
+			&i.ThumbName,
+			&i.OriginalName,
+			&i.MimeType,
+			&i.Size,
+			&i.Sha256,
+			&i.Date,
 		); err != nil {
 			return nil, err
 		}
@@ -186,27 +560,194 @@ func (q *Queries) GetThreadReplies(ctx context.Context, threadID int32) ([]Reply
 	return items, nil
 }
 
-const getThreads = `-- name: GetThreads :many
-SELECT thread_id, title, comment, date, board_id FROM threads
-ORDER BY date ASC
-LIMIT ?
+const getBoardByName = `-- name: GetBoardByName :one
+SELECT board_id, name, archived, max_threads, max_replies, bump_limit, max_comment, links_enabled, allowed_domains, denied_domains, max_file_size, allowed_types, duplicate_window, force_anon, poster_ids, thread_burst, thread_interval, reply_burst, reply_interval FROM boards
+WHERE name = ?
+LIMIT 1
+`
+
+func (q *Queries) GetBoardByName(ctx context.Context, name string) (Board, error) {
+	row := q.db.QueryRowContext(ctx, getBoardByName, name)
+	var i Board
+	err := row.Scan(
+		&i.BoardID,
+		&i.Name,
+		&i.Archived,
+		&i.MaxThreads,
+		&i.MaxReplies,
+		&i.BumpLimit,
+		&i.MaxComment,
+		&i.LinksEnabled,
+		&i.AllowedDomains,
+		&i.DeniedDomains,
+		&i.MaxFileSize,
+		&i.AllowedTypes,
+		&i.DuplicateWindow,
+		&i.ForceAnon,
+		&i.PosterIds,
+		&i.ThreadBurst,
+		&i.ThreadInterval,
+		&i.ReplyBurst,
+		&i.ReplyInterval,
+	)
+	return i, err
+}
+
+const getBoardCatalog = `-- name: GetBoardCatalog :many
+SELECT threads.thread_id, threads.title, threads.comment, threads.date, threads.last_bumped_at, threads.board_id, threads.status, threads.delete_hash, threads.name, threads.tripcode, threads.poster_id, COUNT(replies.reply_id) AS reply_count, GREATEST(threads.date, COALESCE(MAX(replies.date), threads.date)) AS last_activity FROM threads
+LEFT JOIN replies ON replies.thread_id = threads.thread_id
+WHERE threads.board_id = ? AND threads.status = 'alive'
+GROUP BY threads.thread_id
+ORDER BY threads.last_bumped_at DESC, threads.thread_id DESC
 `
 
-func (q *Queries) GetThreads(ctx context.Context, limit int32) ([]Thread, error) {
-	rows, err := q.db.QueryContext(ctx, getThreads, limit)
+type GetBoardCatalogRow struct {
+	ThreadID     int32
+	Title        string
+	Comment      string
+	Date         time.Time
+	LastBumpedAt time.Time
+	BoardID      int32
+	Status       ThreadsStatus
+	DeleteHash   string
+	Name         string
+	Tripcode     string
+	PosterID     string
+	ReplyCount   int64
+	LastActivity time.Time
+}
+
+func (q *Queries) GetBoardCatalog(ctx context.Context, boardID int32) ([]GetBoardCatalogRow, error) {
+	rows, err := q.db.QueryContext(ctx, getBoardCatalog, boardID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Thread
+	var items []GetBoardCatalogRow
 	for rows.Next() {
-		var i Thread
+		var i GetBoardCatalogRow
 		if err := rows.Scan(
 			&i.ThreadID,
 			&i.Title,
 			&i.Comment,
 			&i.Date,
+			&i.LastBumpedAt,
 			&i.BoardID,
+			&i.Status,
+			&i.DeleteHash,
+			&i.Name,
+			&i.Tripcode,
+			&i.PosterID,
+			&i.ReplyCount,
+			&i.LastActivity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBoardForUpdate = `-- name: GetBoardForUpdate :one
+SELECT board_id, name, archived, max_threads, max_replies, bump_limit, max_comment, links_enabled, allowed_domains, denied_domains, max_file_size, allowed_types, duplicate_window, force_anon, poster_ids, thread_burst, thread_interval, reply_burst, reply_interval FROM boards
+WHERE board_id = ?
+LIMIT 1
+FOR UPDATE
+`
+
+func (q *Queries) GetBoardForUpdate(ctx context.Context, boardID int32) (Board, error) {
+	row := q.db.QueryRowContext(ctx, getBoardForUpdate, boardID)
+	var i Board
+	err := row.Scan(
+		&i.BoardID,
+		&i.Name,
+		&i.Archived,
+		&i.MaxThreads,
+		&i.MaxReplies,
+		&i.BumpLimit,
+		&i.MaxComment,
+		&i.LinksEnabled,
+		&i.AllowedDomains,
+		&i.DeniedDomains,
+		&i.MaxFileSize,
+		&i.AllowedTypes,
+		&i.DuplicateWindow,
+		&i.ForceAnon,
+		&i.PosterIds,
+		&i.ThreadBurst,
+		&i.ThreadInterval,
+		&i.ReplyBurst,
+		&i.ReplyInterval,
+	)
+	return i, err
+}
+
+const getBoardPreviews = `-- name: GetBoardPreviews :many
+SELECT r.reply_id, r.comment, r.date, r.sage, r.deleted, r.name, r.tripcode, r.thread_id, r.reply_total FROM (
+	SELECT replies.*,
+		ROW_NUMBER() OVER (PARTITION BY replies.thread_id ORDER BY replies.date DESC, replies.reply_id DESC) AS reply_rank,
+		COUNT(*) OVER (PARTITION BY replies.thread_id) AS reply_total
+	FROM replies
+	JOIN (
+		SELECT thread_id FROM threads
+		WHERE board_id = ? AND status = 'alive'
+		ORDER BY last_bumped_at DESC, thread_id DESC
+		LIMIT ? OFFSET ?
+	) AS page ON page.thread_id = replies.thread_id
+) AS r
+WHERE r.reply_rank <= ?
+ORDER BY r.thread_id, r.date ASC, r.reply_id ASC
+`
+
+type GetBoardPreviewsParams struct {
+	BoardID  int32
+	Limit    int32
+	Offset   int32
+	Previews int64
+}
+
+type GetBoardPreviewsRow struct {
+	ReplyID    int32
+	Comment    string
+	Date       time.Time
+	Sage       bool
+	Deleted    bool
+	Name       string
+	Tripcode   string
+	ThreadID   int32
+	ReplyTotal int64
+}
+
+func (q *Queries) GetBoardPreviews(ctx context.Context, arg GetBoardPreviewsParams) ([]GetBoardPreviewsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getBoardPreviews,
+		arg.BoardID,
+		arg.Limit,
+		arg.Offset,
+		arg.Previews,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBoardPreviewsRow
+	for rows.Next() {
+		var i GetBoardPreviewsRow
+		if err := rows.Scan(
+			&i.ReplyID,
+			&i.Comment,
+			&i.Date,
+			&i.Sage,
+			&i.Deleted,
+			&i.Name,
+			&i.Tripcode,
+			&i.ThreadID,
+			&i.ReplyTotal,
 		); err != nil {
 			return nil, err
 		}
@@ -220,3 +761,753 @@ func (q *Queries) GetThreads(ctx context.Context, limit int32) ([]Thread, error)
 	}
 	return items, nil
 }
+
+const getBoardQuotes = `-- name: GetBoardQuotes :many
+SELECT quotes.quote_id, quotes.thread_id, quotes.reply_id, quotes.quoted_thread_id, quotes.quoted_reply_id, quotes.quoted_board FROM quotes
+JOIN (
+	SELECT thread_id FROM threads
+	WHERE board_id = ? AND status = 'alive'
+	ORDER BY last_bumped_at DESC, thread_id DESC
+	LIMIT ? OFFSET ?
+) AS page ON page.thread_id = quotes.thread_id
+`
+
+type GetBoardQuotesParams struct {
+	BoardID int32
+	Limit   int32
+	Offset  int32
+}
+
+func (q *Queries) GetBoardQuotes(ctx context.Context, arg GetBoardQuotesParams) ([]Quote, error) {
+	rows, err := q.db.QueryContext(ctx, getBoardQuotes, arg.BoardID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Quote
+	for rows.Next() {
+		var i Quote
+		if err := rows.Scan(
+			&i.QuoteID,
+			&i.ThreadID,
+			&i.ReplyID,
+			&i.QuotedThreadID,
+			&i.QuotedReplyID,
+			&i.QuotedBoard,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBoardThreads = `-- name: GetBoardThreads :many
+SELECT thread_id, title, comment, date, last_bumped_at, board_id, status, delete_hash, name, tripcode, poster_id FROM threads
+WHERE board_id = ? AND status = 'alive'
+ORDER BY last_bumped_at DESC, thread_id DESC
+LIMIT ? OFFSET ?
+`
+
+type GetBoardThreadsParams struct {
+	BoardID int32
+	Limit   int32
+	Offset  int32
+}
+
+func (q *Queries) GetBoardThreads(ctx context.Context, arg GetBoardThreadsParams) ([]Thread, error) {
+	rows, err := q.db.QueryContext(ctx, getBoardThreads, arg.BoardID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Thread
+	for rows.Next() {
+		var i Thread
+		if err := rows.Scan(
+			&i.ThreadID,
+			&i.Title,
+			&i.Comment,
+			&i.Date,
+			&i.LastBumpedAt,
+			&i.BoardID,
+			&i.Status,
+			&i.DeleteHash,
+			&i.Name,
+			&i.Tripcode,
+			&i.PosterID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOldestThread = `-- name: GetOldestThread :one
+SELECT thread_id, title, comment, date, last_bumped_at, board_id, status, delete_hash, name, tripcode, poster_id FROM threads 
+WHERE board_id = ? AND status = 'alive'
+ORDER BY last_bumped_at ASC, thread_id ASC
+LIMIT 1
+`
+
+func (q *Queries) GetOldestThread(ctx context.Context, boardID int32) (Thread, error) {
+	row := q.db.QueryRowContext(ctx, getOldestThread, boardID)
+	var i Thread
+	err := row.Scan(
+		&i.ThreadID,
+		&i.Title,
+		&i.Comment,
+		&i.Date,
+		&i.LastBumpedAt,
+		&i.BoardID,
+		&i.Status,
+		&i.DeleteHash,
+		&i.Name,
+		&i.Tripcode,
+		&i.PosterID,
+	)
+	return i, err
+}
+
+const getRateLimitForUpdate = `-- name: GetRateLimitForUpdate :one
+SELECT bucket_key, tokens, updated_at FROM rate_limits
+WHERE bucket_key = ?
+FOR UPDATE
+`
+
+func (q *Queries) GetRateLimitForUpdate(ctx context.Context, bucketKey string) (RateLimit, error) {
+	row := q.db.QueryRowContext(ctx, getRateLimitForUpdate, bucketKey)
+	var i RateLimit
+	err := row.Scan(&i.BucketKey, &i.Tokens, &i.UpdatedAt)
+	return i, err
+}
+
+const getRecentReplies = `-- name: GetRecentReplies :many
+SELECT replies.reply_id, replies.comment, replies.date, threads.thread_id, threads.title, boards.name AS board_name FROM replies
+JOIN threads ON threads.thread_id = replies.thread_id
+JOIN boards ON boards.board_id = threads.board_id
+WHERE threads.status = 'alive' AND replies.deleted = FALSE
+ORDER BY replies.date DESC, replies.reply_id DESC
+LIMIT ?
+`
+
+type GetRecentRepliesRow struct {
+	ReplyID   int32
+	Comment   string
+	Date      time.Time
+	ThreadID  int32
+	Title     string
+	BoardName string
+}
+
+func (q *Queries) GetRecentReplies(ctx context.Context, limit int32) ([]GetRecentRepliesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentReplies, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRecentRepliesRow
+	for rows.Next() {
+		var i GetRecentRepliesRow
+		if err := rows.Scan(
+			&i.ReplyID,
+			&i.Comment,
+			&i.Date,
+			&i.ThreadID,
+			&i.Title,
+			&i.BoardName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentThreads = `-- name: GetRecentThreads :many
+SELECT threads.thread_id, threads.title, threads.comment, threads.date, boards.name AS board_name FROM threads
+JOIN boards ON boards.board_id = threads.board_id
+WHERE threads.status = 'alive'
+ORDER BY threads.date DESC, threads.thread_id DESC
+LIMIT ?
+`
+
+type GetRecentThreadsRow struct {
+	ThreadID  int32
+	Title     string
+	Comment   string
+	Date      time.Time
+	BoardName string
+}
+
+func (q *Queries) GetRecentThreads(ctx context.Context, limit int32) ([]GetRecentThreadsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentThreads, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRecentThreadsRow
+	for rows.Next() {
+		var i GetRecentThreadsRow
+		if err := rows.Scan(
+			&i.ThreadID,
+			&i.Title,
+			&i.Comment,
+			&i.Date,
+			&i.BoardName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReply = `-- name: GetReply :one
+SELECT reply_id, comment, date, sage, deleted, delete_hash, name, tripcode, poster_id, thread_id FROM replies
+WHERE reply_id = ?
+LIMIT 1
+`
+
+func (q *Queries) GetReply(ctx context.Context, replyID int32) (Reply, error) {
+	row := q.db.QueryRowContext(ctx, getReply, replyID)
+	var i Reply
+	err := row.Scan(
+		&i.ReplyID,
+		&i.Comment,
+		&i.Date,
+		&i.Sage,
+		&i.Deleted,
+		&i.DeleteHash,
+		&i.Name,
+		&i.Tripcode,
+		&i.PosterID,
+		&i.ThreadID,
+	)
+	return i, err
+}
+
+const getReplyAttachments = `-- name: GetReplyAttachments :many
+SELECT attachment_id, thread_id, reply_id, file_name, thumb_name, original_name, mime_type, size, sha256, date FROM attachments
+WHERE reply_id = ?
+ORDER BY attachment_id ASC
+`
+
+func (q *Queries) GetReplyAttachments(ctx context.Context, replyID sql.NullInt32) ([]Attachment, error) {
+	rows, err := q.db.QueryContext(ctx, getReplyAttachments, replyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Attachment
+	for rows.Next() {
+		var i Attachment
+		if err := rows.Scan(
+			&i.AttachmentID,
+			&i.ThreadID,
+			&i.ReplyID,
+			&i.FileName,
+			&i.ThumbName,
+			&i.OriginalName,
+			&i.MimeType,
+			&i.Size,
+			&i.Sha256,
+			&i.Date,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getThread = `-- name: GetThread :one
+SELECT thread_id, title, comment, date, last_bumped_at, board_id, status, delete_hash, name, tripcode, poster_id FROM threads
+WHERE thread_id = ?
+LIMIT 1
+`
+
+func (q *Queries) GetThread(ctx context.Context, threadID int32) (Thread, error) {
+	row := q.db.QueryRowContext(ctx, getThread, threadID)
+	var i Thread
+	err := row.Scan(
+		&i.ThreadID,
+		&i.Title,
+		&i.Comment,
+		&i.Date,
+		&i.LastBumpedAt,
+		&i.BoardID,
+		&i.Status,
+		&i.DeleteHash,
+		&i.Name,
+		&i.Tripcode,
+		&i.PosterID,
+	)
+	return i, err
+}
+
+const getThreadAttachments = `-- name: GetThreadAttachments :many
+SELECT attachment_id, thread_id, reply_id, file_name, thumb_name, original_name, mime_type, size, sha256, date FROM attachments
+WHERE thread_id = ?
+ORDER BY attachment_id ASC
+`
+
+func (q *Queries) GetThreadAttachments(ctx context.Context, threadID int32) ([]Attachment, error) {
+	rows, err := q.db.QueryContext(ctx, getThreadAttachments, threadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Attachment
+	for rows.Next() {
+		var i Attachment
+		if err := rows.Scan(
+			&i.AttachmentID,
+			&i.ThreadID,
+			&i.ReplyID,
+			&i.FileName,
+			&i.ThumbName,
+			&i.OriginalName,
+			&i.MimeType,
+			&i.Size,
+			&i.Sha256,
+			&i.Date,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getThreadBacklinks = `-- name: GetThreadBacklinks :many
+SELECT quotes.reply_id, quotes.quoted_reply_id, quotes.thread_id, boards.name AS board_name FROM quotes
+JOIN threads ON threads.thread_id = quotes.thread_id
+JOIN boards ON boards.board_id = threads.board_id
+LEFT JOIN replies ON replies.reply_id = quotes.reply_id AND replies.deleted = FALSE
+WHERE quotes.quoted_thread_id = ? AND threads.status <> 'deleted'
+AND (quotes.reply_id IS NULL OR replies.reply_id IS NOT NULL)
+ORDER BY quotes.quote_id ASC
+`
+
+type GetThreadBacklinksRow struct {
+	ReplyID       sql.NullInt32
+	QuotedReplyID sql.NullInt32
+	ThreadID      int32
+	BoardName     string
+}
+
+func (q *Queries) GetThreadBacklinks(ctx context.Context, quotedThreadID int32) ([]GetThreadBacklinksRow, error) {
+	rows, err := q.db.QueryContext(ctx, getThreadBacklinks, quotedThreadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetThreadBacklinksRow
+	for rows.Next() {
+		var i GetThreadBacklinksRow
+		if err := rows.Scan(
+			&i.ReplyID,
+			&i.QuotedReplyID,
+			&i.ThreadID,
+			&i.BoardName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getThreadForUpdate = `-- name: GetThreadForUpdate :one
+SELECT thread_id, title, comment, date, last_bumped_at, board_id, status, delete_hash, name, tripcode, poster_id FROM threads
+WHERE thread_id = ?
+LIMIT 1
+FOR UPDATE
+`
+
+func (q *Queries) GetThreadForUpdate(ctx context.Context, threadID int32) (Thread, error) {
+	row := q.db.QueryRowContext(ctx, getThreadForUpdate, threadID)
+	var i Thread
+	err := row.Scan(
+		&i.ThreadID,
+		&i.Title,
+		&i.Comment,
+		&i.Date,
+		&i.LastBumpedAt,
+		&i.BoardID,
+		&i.Status,
+		&i.DeleteHash,
+		&i.Name,
+		&i.Tripcode,
+		&i.PosterID,
+	)
+	return i, err
+}
+
+const getThreadQuotes = `-- name: GetThreadQuotes :many
+SELECT quote_id, thread_id, reply_id, quoted_thread_id, quoted_reply_id, quoted_board FROM quotes
+WHERE thread_id = ?
+`
+
+func (q *Queries) GetThreadQuotes(ctx context.Context, threadID int32) ([]Quote, error) {
+	rows, err := q.db.QueryContext(ctx, getThreadQuotes, threadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Quote
+	for rows.Next() {
+		var i Quote
+		if err := rows.Scan(
+			&i.QuoteID,
+			&i.ThreadID,
+			&i.ReplyID,
+			&i.QuotedThreadID,
+			&i.QuotedReplyID,
+			&i.QuotedBoard,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getThreadReplies = `-- name: GetThreadReplies :many
+SELECT reply_id, comment, date, sage, deleted, delete_hash, name, tripcode, poster_id, thread_id FROM replies
+WHERE thread_id = ?
+ORDER BY date ASC, reply_id ASC
+`
+
+func (q *Queries) GetThreadReplies(ctx context.Context, threadID int32) ([]Reply, error) {
+	rows, err := q.db.QueryContext(ctx, getThreadReplies, threadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Reply
+	for rows.Next() {
+		var i Reply
+		if err := rows.Scan(
+			&i.ReplyID,
+			&i.Comment,
+			&i.Date,
+			&i.Sage,
+			&i.Deleted,
+			&i.DeleteHash,
+			&i.Name,
+			&i.Tripcode,
+			&i.PosterID,
+			&i.ThreadID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listBannedHashes = `-- name: ListBannedHashes :many
+SELECT sha256, reason, date FROM banned_hashes
+ORDER BY date DESC
+`
+
+func (q *Queries) ListBannedHashes(ctx context.Context) ([]BannedHash, error) {
+	rows, err := q.db.QueryContext(ctx, listBannedHashes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BannedHash
+	for rows.Next() {
+		var i BannedHash
+		if err := rows.Scan(&i.Sha256, &i.Reason, &i.Date); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listBoards = `-- name: ListBoards :many
+SELECT board_id, name, archived, max_threads, max_replies, bump_limit, max_comment, links_enabled, allowed_domains, denied_domains, max_file_size, allowed_types, duplicate_window, force_anon, poster_ids, thread_burst, thread_interval, reply_burst, reply_interval FROM boards
+ORDER BY board_id ASC
+`
+
+func (q *Queries) ListBoards(ctx context.Context) ([]Board, error) {
+	rows, err := q.db.QueryContext(ctx, listBoards)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Board
+	for rows.Next() {
+		var i Board
+		if err := rows.Scan(
+			&i.BoardID,
+			&i.Name,
+			&i.Archived,
+			&i.MaxThreads,
+			&i.MaxReplies,
+			&i.BumpLimit,
+			&i.MaxComment,
+			&i.LinksEnabled,
+			&i.AllowedDomains,
+			&i.DeniedDomains,
+			&i.MaxFileSize,
+			&i.AllowedTypes,
+			&i.DuplicateWindow,
+			&i.ForceAnon,
+			&i.PosterIds,
+			&i.ThreadBurst,
+			&i.ThreadInterval,
+			&i.ReplyBurst,
+			&i.ReplyInterval,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const renameBoard = `-- name: RenameBoard :execresult
+UPDATE boards SET name = ?
+WHERE board_id = ?
+`
+
+type RenameBoardParams struct {
+	Name    string
+	BoardID int32
+}
+
+func (q *Queries) RenameBoard(ctx context.Context, arg RenameBoardParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, renameBoard, arg.Name, arg.BoardID)
+}
+
+const setBoardArchived = `-- name: SetBoardArchived :execresult
+UPDATE boards SET archived = ?
+WHERE board_id = ?
+`
+
+type SetBoardArchivedParams struct {
+	Archived bool
+	BoardID  int32
+}
+
+func (q *Queries) SetBoardArchived(ctx context.Context, arg SetBoardArchivedParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, setBoardArchived, arg.Archived, arg.BoardID)
+}
+
+const setBoardForceAnon = `-- name: SetBoardForceAnon :execresult
+UPDATE boards SET force_anon = ?
+WHERE board_id = ?
+`
+
+type SetBoardForceAnonParams struct {
+	ForceAnon bool
+	BoardID   int32
+}
+
+func (q *Queries) SetBoardForceAnon(ctx context.Context, arg SetBoardForceAnonParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, setBoardForceAnon, arg.ForceAnon, arg.BoardID)
+}
+
+const setBoardPosterIDs = `-- name: SetBoardPosterIDs :execresult
+UPDATE boards SET poster_ids = ?
+WHERE board_id = ?
+`
+
+type SetBoardPosterIDsParams struct {
+	PosterIds bool
+	BoardID   int32
+}
+
+func (q *Queries) SetBoardPosterIDs(ctx context.Context, arg SetBoardPosterIDsParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, setBoardPosterIDs, arg.PosterIds, arg.BoardID)
+}
+
+const setThreadPosterID = `-- name: SetThreadPosterID :execresult
+UPDATE threads SET poster_id = ?
+WHERE thread_id = ?
+`
+
+type SetThreadPosterIDParams struct {
+	PosterID string
+	ThreadID int32
+}
+
+func (q *Queries) SetThreadPosterID(ctx context.Context, arg SetThreadPosterIDParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, setThreadPosterID, arg.PosterID, arg.ThreadID)
+}
+
+const updateBoardFiles = `-- name: UpdateBoardFiles :execresult
+UPDATE boards SET max_file_size = ?, allowed_types = ?, duplicate_window = ?
+WHERE board_id = ?
+`
+
+type UpdateBoardFilesParams struct {
+	MaxFileSize     int32
+	AllowedTypes    string
+	DuplicateWindow int32
+	BoardID         int32
+}
+
+func (q *Queries) UpdateBoardFiles(ctx context.Context, arg UpdateBoardFilesParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, updateBoardFiles,
+		arg.MaxFileSize,
+		arg.AllowedTypes,
+		arg.DuplicateWindow,
+		arg.BoardID,
+	)
+}
+
+const updateBoardLimits = `-- name: UpdateBoardLimits :execresult
+UPDATE boards SET max_threads = ?, max_replies = ?, bump_limit = ?, max_comment = ?
+WHERE board_id = ?
+`
+
+type UpdateBoardLimitsParams struct {
+	MaxThreads int32
+	MaxReplies int32
+	BumpLimit  int32
+	MaxComment int32
+	BoardID    int32
+}
+
+func (q *Queries) UpdateBoardLimits(ctx context.Context, arg UpdateBoardLimitsParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, updateBoardLimits,
+		arg.MaxThreads,
+		arg.MaxReplies,
+		arg.BumpLimit,
+		arg.MaxComment,
+		arg.BoardID,
+	)
+}
+
+const updateBoardLinks = `-- name: UpdateBoardLinks :execresult
+UPDATE boards SET links_enabled = ?, allowed_domains = ?, denied_domains = ?
+WHERE board_id = ?
+`
+
+type UpdateBoardLinksParams struct {
+	LinksEnabled   bool
+	AllowedDomains string
+	DeniedDomains  string
+	BoardID        int32
+}
+
+func (q *Queries) UpdateBoardLinks(ctx context.Context, arg UpdateBoardLinksParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, updateBoardLinks,
+		arg.LinksEnabled,
+		arg.AllowedDomains,
+		arg.DeniedDomains,
+		arg.BoardID,
+	)
+}
+
+const updateBoardRates = `-- name: UpdateBoardRates :execresult
+UPDATE boards SET thread_burst = ?, thread_interval = ?, reply_burst = ?, reply_interval = ?
+WHERE board_id = ?
+`
+
+type UpdateBoardRatesParams struct {
+	ThreadBurst    int32
+	ThreadInterval int32
+	ReplyBurst     int32
+	ReplyInterval  int32
+	BoardID        int32
+}
+
+func (q *Queries) UpdateBoardRates(ctx context.Context, arg UpdateBoardRatesParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, updateBoardRates,
+		arg.ThreadBurst,
+		arg.ThreadInterval,
+		arg.ReplyBurst,
+		arg.ReplyInterval,
+		arg.BoardID,
+	)
+}
+
+const updateRateLimit = `-- name: UpdateRateLimit :execresult
+UPDATE rate_limits SET tokens = ?, updated_at = ?
+WHERE bucket_key = ?
+`
+
+type UpdateRateLimitParams struct {
+	Tokens    float64
+	UpdatedAt time.Time
+	BucketKey string
+}
+
+func (q *Queries) UpdateRateLimit(ctx context.Context, arg UpdateRateLimitParams) (sql.Result, error) {
+	return q.db.ExecContext(ctx, updateRateLimit, arg.Tokens, arg.UpdatedAt, arg.BucketKey)
+}
@@ -1,110 +1,1413 @@
 package utils
 
 import (
+    "errors"
+    "database/sql"
     "strconv"
     "net/http"
     "context"
     "strings"
+    "regexp"
+    "unicode"
+    "unicode/utf8"
+    "net/url"
     "html/template"
     "path/filepath"
     "log"
+    "time"
+    "sort"
+    "fmt"
+    "io"
+    "mime/multipart"
+    "crypto/sha256"
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/subtle"
+    "encoding/hex"
+    "encoding/base64"
+    "image"
+    "image/draw"
+    "image/gif"
+    "image/jpeg"
+    "image/png"
 
     "github.com/enzdor/gomsg/models"
     "github.com/enzdor/gomsg/sqlc"
 )
 
+var funcs = template.FuncMap{
+    "ago": TimeAgo,
+    "fulldate": FullDate,
+    "truncate": Truncate,
+    "comment": RenderComment,
+    "filesize": FileSize,
+    "isimage": IsImage,
+}
+
 func Serve(page string) *template.Template {
 	lp := filepath.Join("templates", "layout.html")
 	fp := filepath.Join("templates", page + ".html")
 
-	tmpl, err := template.ParseFiles(lp, fp)
+	tmpl, err := template.New(filepath.Base(lp)).Funcs(funcs).ParseFiles(lp, fp)
 	if err != nil {
 	    log.Fatal(err)
 	}
 
-	return tmpl
+	return tmpl
+}
+
+// TimeAgo describes how long ago t was, falling back to the plain date for
+// anything older than a month.
+func TimeAgo(t time.Time) string {
+    d := time.Since(t)
+
+    switch {
+    case d < time.Minute:
+	return "just now"
+    case d < time.Hour:
+	return plural(int(d / time.Minute), "minute") + " ago"
+    case d < 24 * time.Hour:
+	return plural(int(d / time.Hour), "hour") + " ago"
+    case d < 30 * 24 * time.Hour:
+	return plural(int(d / (24 * time.Hour)), "day") + " ago"
+    }
+
+    return t.UTC().Format("2 Jan 2006")
+}
+
+// FullDate is the absolute form of a date, shown when hovering over TimeAgo.
+func FullDate(t time.Time) string {
+    return t.UTC().Format("Mon, 2 Jan 2006 15:04:05 MST")
+}
+
+// Truncate shortens s to at most n characters, marking the cut with an
+// ellipsis.
+func Truncate(n int, s string) string {
+    if utf8.RuneCountInString(s) <= n {
+	return s
+    }
+
+    return string([]rune(s)[:n]) + "…"
+}
+
+func plural(n int, unit string) string {
+    if n == 1 {
+	return "1 " + unit
+    }
+    return strconv.Itoa(n) + " " + unit + "s"
+}
+
+func ValidatePost(title string, comment string, maxComment int32) ([2]models.FormError, error) {
+    errors := [2]models.FormError{
+	{
+	    Bool: false,
+	    Message: "",
+	    Field: "title",
+	},
+	{
+	    Bool: false,
+	    Message: "",
+	    Field: "comment",
+	},
+    }
+
+    if strings.TrimSpace(title) == "" {
+	errors[0] = models.FormError{
+	    Bool: true,
+	    Message: "This field is required",
+	    Field: "title",
+	}
+
+    }
+
+    if strings.TrimSpace(comment) == "" {
+	errors[1] = models.FormError{
+	    Bool: true,
+	    Message: "This field is required",
+	    Field: "comment",
+	}
+
+    } else if utf8.RuneCountInString(comment) > int(maxComment) {
+	errors[1] = models.FormError{
+	    Bool: true,
+	    Message: "This field can have at most " + strconv.Itoa(int(maxComment)) + " characters",
+	    Field: "comment",
+	}
+
+    }
+
+    if errors[0].Bool || errors[1].Bool {
+	err := &models.ValidateError{Message: "One of the fields has not passed the required validation rules."}
+	return errors, err
+    }
+
+    return errors, nil
+}
+
+func ValidateReply(title string, maxComment int32) (models.FormError, error) {
+    error := models.FormError{
+	Bool: false,
+	Message: "",
+	Field: "comment",
+    }
+
+    if strings.TrimSpace(title) == "" {
+	error = models.FormError{
+	    Bool: true,
+	    Message: "This field is required",
+	    Field: "comment",
+	}
+
+    } else if utf8.RuneCountInString(title) > int(maxComment) {
+	error = models.FormError{
+	    Bool: true,
+	    Message: "This field can have at most " + strconv.Itoa(int(maxComment)) + " characters",
+	    Field: "comment",
+	}
+
+    }
+
+    if error.Bool {
+	err := &models.ValidateError{Message: "The field has not passed the required validation rules."}
+	return error, err
+    }
+
+    return error, nil
+}
+
+var quoteRegexp = regexp.MustCompile(`>>>/([a-z0-9]+)/([0-9]+)|>>([0-9]+)`)
+
+// QuoteRef is a post quoted in a comment, either a reply by its id or a
+// thread by its board and id.
+type QuoteRef struct {
+    Board string
+    ThreadID int32
+    ReplyID int32
+}
+
+func quoteRef(comment string, loc []int) (QuoteRef, bool) {
+    ref := QuoteRef{}
+
+    if loc[2] >= 0 {
+	id, err := strconv.ParseInt(comment[loc[4]:loc[5]], 10, 32)
+	if err != nil {
+	    return ref, false
+	}
+	ref.Board = comment[loc[2]:loc[3]]
+	ref.ThreadID = int32(id)
+	return ref, true
+    }
+
+    id, err := strconv.ParseInt(comment[loc[6]:loc[7]], 10, 32)
+    if err != nil {
+	return ref, false
+    }
+    ref.ReplyID = int32(id)
+    return ref, true
+}
+
+// ParseQuotes finds the posts quoted in a comment, >>123 quotes reply 123
+// and >>>/board/123 quotes thread 123 on board. Each post is returned once.
+func ParseQuotes(comment string) []QuoteRef {
+    refs := []QuoteRef{}
+    seen := map[QuoteRef]bool{}
+
+    for _, loc := range quoteRegexp.FindAllStringSubmatchIndex(comment, -1) {
+	ref, ok := quoteRef(comment, loc)
+	if !ok || seen[ref] {
+	    continue
+	}
+	seen[ref] = true
+	refs = append(refs, ref)
+    }
+
+    return refs
+}
+
+func quoteHref(ref QuoteRef, quotes []sqlc.Quote) string {
+    for _, quote := range quotes {
+	if ref.ReplyID != 0 && quote.QuotedReplyID.Valid && quote.QuotedReplyID.Int32 == ref.ReplyID {
+	    return "/thread/" + strconv.Itoa(int(quote.QuotedThreadID)) + "#r" + strconv.Itoa(int(ref.ReplyID))
+	}
+	if ref.ReplyID == 0 && !quote.QuotedReplyID.Valid && quote.QuotedThreadID == ref.ThreadID && quote.QuotedBoard == ref.Board {
+	    return "/thread/" + strconv.Itoa(int(ref.ThreadID))
+	}
+    }
+
+    return ""
+}
+
+var urlRegexp = regexp.MustCompile(`https?://[^\s<>"\[\]]+`)
+
+// splitDomains splits a list of domains separated by commas or spaces.
+func splitDomains(list string) []string {
+    return strings.FieldsFunc(strings.ToLower(list), func(r rune) bool {
+	return r == ',' || unicode.IsSpace(r)
+    })
+}
+
+func matchesDomain(host string, domains []string) bool {
+    for _, domain := range domains {
+	if host == domain || strings.HasSuffix(host, "." + domain) {
+	    return true
+	}
+    }
+    return false
+}
+
+// LinkAllowed reports whether a link to rawURL can be rendered on board.
+// Links have to be enabled on the board, go to an allowed domain if the
+// board has any and not go to a denied one.
+func LinkAllowed(board sqlc.Board, rawURL string) bool {
+    if !board.LinksEnabled {
+	return false
+    }
+
+    u, err := url.Parse(rawURL)
+    if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+	return false
+    }
+    host := strings.ToLower(u.Hostname())
+
+    if allowed := splitDomains(board.AllowedDomains); len(allowed) > 0 && !matchesDomain(host, allowed) {
+	return false
+    }
+
+    return !matchesDomain(host, splitDomains(board.DeniedDomains))
+}
+
+// asterisks are escaped as well so that bold and italics can not reach into
+// a link
+var linkReplacer = strings.NewReplacer("*", "&#42;")
+
+// renderText escapes s and turns the URLs the board allows into links.
+func renderText(s string, board sqlc.Board) string {
+    var b strings.Builder
+    last := 0
+
+    for _, loc := range urlRegexp.FindAllStringIndex(s, -1) {
+	// punctuation at the end of a URL most likely ends the sentence
+	end := loc[0] + len(strings.TrimRight(s[loc[0]:loc[1]], ".,;:!?)'"))
+	link := s[loc[0]:end]
+	if !LinkAllowed(board, link) {
+	    continue
+	}
+
+	escaped := linkReplacer.Replace(template.HTMLEscapeString(link))
+	b.WriteString(template.HTMLEscapeString(s[last:loc[0]]))
+	b.WriteString(`<a href="` + escaped + `" rel="nofollow ugc noopener" target="_blank">` + escaped + `</a>`)
+	last = end
+    }
+    b.WriteString(template.HTMLEscapeString(s[last:]))
+
+    return b.String()
+}
+
+// renderQuotes escapes s, links the quotes that were stored for it and the
+// URLs the board allows. Quotes of posts that did not exist when it was
+// posted stay text.
+func renderQuotes(s string, quotes []sqlc.Quote, board sqlc.Board) string {
+    var b strings.Builder
+    last := 0
+
+    for _, loc := range quoteRegexp.FindAllStringSubmatchIndex(s, -1) {
+	b.WriteString(renderText(s[last:loc[0]], board))
+	text := template.HTMLEscapeString(s[loc[0]:loc[1]])
+	last = loc[1]
+
+	ref, ok := quoteRef(s, loc)
+	href := ""
+	if ok {
+	    href = quoteHref(ref, quotes)
+	}
+	if href == "" {
+	    b.WriteString(text)
+	    continue
+	}
+	b.WriteString(`<a class="quote" href="` + href + `">` + text + `</a>`)
+    }
+    b.WriteString(renderText(s[last:], board))
+
+    return b.String()
+}
+
+// The markup runs on text that has already been escaped, so none of it can
+// match inside a tag. Bold and italics stop at tags so they can not cross
+// the boundaries of a link or a spoiler.
+var (
+    spoilerRegexp = regexp.MustCompile(`\[spoiler\](.+?)\[/spoiler\]`)
+    boldRegexp = regexp.MustCompile(`\*\*([^*<\s](?:[^*<]*[^*<\s])?)\*\*`)
+    italicRegexp = regexp.MustCompile(`\*([^*<\s](?:[^*<]*[^*<\s])?)\*`)
+)
+
+func renderLine(line string, quotes []sqlc.Quote, board sqlc.Board) string {
+    html := renderQuotes(line, quotes, board)
+    html = spoilerRegexp.ReplaceAllString(html, `<span class="spoiler">$1</span>`)
+    html = boldRegexp.ReplaceAllString(html, `<strong>$1</strong>`)
+    html = italicRegexp.ReplaceAllString(html, `<em>$1</em>`)
+
+    // a line starting with > is greentext, unless it starts with a quote
+    if strings.HasPrefix(line, ">") {
+	if loc := quoteRegexp.FindStringIndex(line); loc == nil || loc[0] != 0 {
+	    html = `<span class="greentext">` + html + `</span>`
+	}
+    }
+
+    return html
+}
+
+// RenderComment turns a comment into HTML. Every piece of the comment is
+// escaped before any markup is added. Lines between ``` fences become a code
+// block, which is left as it is, and every other line gets its quotes
+// linked, greentext, [spoiler]spoilers[/spoiler], **bold** and *italics*.
+// URLs become links when the board allows them.
+func RenderComment(comment string, quotes []sqlc.Quote, board sqlc.Board) template.HTML {
+    lines := strings.Split(strings.ReplaceAll(comment, "\r\n", "\n"), "\n")
+    out := []string{}
+    code := []string{}
+    inCode := false
+
+    for _, line := range lines {
+	if strings.TrimSpace(line) == "```" {
+	    if inCode {
+		out = append(out, "<pre><code>" + strings.Join(code, "\n") + "</code></pre>")
+		code = []string{}
+	    }
+	    inCode = !inCode
+	    continue
+	}
+
+	if inCode {
+	    code = append(code, template.HTMLEscapeString(line))
+	    continue
+	}
+	out = append(out, renderLine(line, quotes, board))
+    }
+
+    // an unclosed fence runs to the end of the comment
+    if inCode {
+	out = append(out, "<pre><code>" + strings.Join(code, "\n") + "</code></pre>")
+    }
+
+    html := ""
+    for i, line := range out {
+	if i > 0 && !strings.HasPrefix(line, "<pre>") && !strings.HasPrefix(out[i - 1], "<pre>") {
+	    html += "<br>"
+	}
+	html += line
+    }
+
+    return template.HTML(html)
+}
+
+var boardNameRegexp = regexp.MustCompile(`^[a-z0-9]+$`)
+
+func ValidateBoardName(name string) (models.FormError, error) {
+    error := models.FormError{
+	Bool: false,
+	Message: "",
+	Field: "name",
+    }
+
+    if strings.TrimSpace(name) == "" {
+	error = models.FormError{
+	    Bool: true,
+	    Message: "This field is required",
+	    Field: "name",
+	}
+    } else if len(name) > 100 || !boardNameRegexp.MatchString(name) {
+	error = models.FormError{
+	    Bool: true,
+	    Message: "Board names can only contain up to 100 lowercase letters and numbers",
+	    Field: "name",
+	}
+    }
+
+    if error.Bool {
+	err := &models.ValidateError{Message: "The field has not passed the required validation rules."}
+	return error, err
+    }
+
+    return error, nil
+}
+
+// MaxCommentLength is the size of the comment columns, no board can allow
+// longer comments than this.
+const MaxCommentLength = 1275
+
+func ValidateBoardLimits(threads string, replies string, bump string, comment string) (sqlc.UpdateBoardLimitsParams, models.FormError, error) {
+    params := sqlc.UpdateBoardLimitsParams{}
+    error := models.FormError{
+	Bool: false,
+	Message: "",
+	Field: "limits",
+    }
+
+    t, terr := strconv.Atoi(threads)
+    r, rerr := strconv.Atoi(replies)
+    b, berr := strconv.Atoi(bump)
+    c, cerr := strconv.Atoi(comment)
+
+    if terr != nil || rerr != nil || berr != nil || cerr != nil || t < 1 || r < 1 || b < 0 || c < 1 {
+	error = models.FormError{
+	    Bool: true,
+	    Message: "Limits have to be positive numbers",
+	    Field: "limits",
+	}
+    } else if c > MaxCommentLength {
+	error = models.FormError{
+	    Bool: true,
+	    Message: "Comments can have at most " + strconv.Itoa(MaxCommentLength) + " characters",
+	    Field: "limits",
+	}
+    }
+
+    if error.Bool {
+	err := &models.ValidateError{Message: "The limits have not passed the required validation rules."}
+	return params, error, err
+    }
+
+    params.MaxThreads = int32(t)
+    params.MaxReplies = int32(r)
+    params.BumpLimit = int32(b)
+    params.MaxComment = int32(c)
+
+    return params, error, nil
+}
+
+var domainRegexp = regexp.MustCompile(`^[a-z0-9-]+(\.[a-z0-9-]+)*$`)
+
+// ValidateBoardLinks checks the allowed and denied domains of a board and
+// returns them as the parameters to store, one comma separated list each.
+func ValidateBoardLinks(enabled bool, allowed string, denied string) (sqlc.UpdateBoardLinksParams, models.FormError, error) {
+    params := sqlc.UpdateBoardLinksParams{LinksEnabled: enabled}
+    error := models.FormError{
+	Bool: false,
+	Message: "",
+	Field: "links",
+    }
+
+    lists := [2][]string{splitDomains(allowed), splitDomains(denied)}
+    for _, domains := range lists {
+	for _, domain := range domains {
+	    if !domainRegexp.MatchString(domain) {
+		error = models.FormError{
+		    Bool: true,
+		    Message: "\"" + domain + "\" is not a valid domain",
+		    Field: "links",
+		}
+	    }
+	}
+    }
+
+    params.AllowedDomains = strings.Join(lists[0], ", ")
+    params.DeniedDomains = strings.Join(lists[1], ", ")
+    if !error.Bool && (len(params.AllowedDomains) > 1000 || len(params.DeniedDomains) > 1000) {
+	error = models.FormError{
+	    Bool: true,
+	    Message: "Domain lists can have at most 1000 characters",
+	    Field: "links",
+	}
+    }
+
+    if error.Bool {
+	err := &models.ValidateError{Message: "The domains have not passed the required validation rules."}
+	return params, error, err
+    }
+
+    return params, error, nil
+}
+
+// FileExtensions maps the content types an upload can be sniffed as to the
+// extension it is stored with. Boards can only allow these types.
+var FileExtensions = map[string]string{
+    "image/jpeg": ".jpg",
+    "image/png": ".png",
+    "image/gif": ".gif",
+    "image/webp": ".webp",
+    "image/bmp": ".bmp",
+    "video/webm": ".webm",
+    "video/mp4": ".mp4",
+    "audio/mpeg": ".mp3",
+    "application/pdf": ".pdf",
+    "text/plain": ".txt",
+}
+
+// MaxFileSize is the largest upload a board can allow, in bytes.
+const MaxFileSize = 20 << 20
+
+// FileSize formats a size in bytes for people.
+func FileSize(size int32) string {
+    switch {
+    case size >= 1 << 20:
+	return fmt.Sprintf("%.1f MB", float64(size) / (1 << 20))
+    case size >= 1 << 10:
+	return fmt.Sprintf("%.1f KB", float64(size) / (1 << 10))
+    default:
+	return strconv.Itoa(int(size)) + " B"
+    }
+}
+
+func IsImage(mimeType string) bool {
+    return strings.HasPrefix(mimeType, "image/")
+}
+
+// ValidateUpload checks a file sent with a post against the limits of the
+// board. The type is sniffed from the first bytes of the file, the name and
+// the type sent by the browser are not trusted, and returned so the file
+// can be stored with it.
+func ValidateUpload(header *multipart.FileHeader, board sqlc.Board) (string, models.FormError, error) {
+    error := models.FormError{
+	Bool: false,
+	Message: "",
+	Field: "file",
+    }
+
+    var mimeType string
+    if board.MaxFileSize == 0 {
+	error = models.FormError{
+	    Bool: true,
+	    Message: "This board does not allow files",
+	    Field: "file",
+	}
+    } else if header.Size > int64(board.MaxFileSize) {
+	error = models.FormError{
+	    Bool: true,
+	    Message: "File can have a size of at most " + FileSize(board.MaxFileSize),
+	    Field: "file",
+	}
+    } else {
+	file, err := header.Open()
+	if err != nil {
+	    return "", error, err
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+	    return "", error, err
+	}
+
+	mimeType, _, _ = strings.Cut(http.DetectContentType(head[:n]), ";")
+	allowed := false
+	for _, t := range splitDomains(board.AllowedTypes) {
+	    if t == mimeType {
+		allowed = true
+	    }
+	}
+	if _, ok := FileExtensions[mimeType]; !ok || !allowed {
+	    error = models.FormError{
+		Bool: true,
+		Message: "This board does not allow files of this type",
+		Field: "file",
+	    }
+	}
+    }
+
+    if error.Bool {
+	err := &models.ValidateError{Message: "The file has not passed the required validation rules."}
+	return "", error, err
+    }
+
+    return mimeType, error, nil
+}
+
+// ThumbnailSize is the largest width and height of a thumbnail.
+const ThumbnailSize = 250
+
+// MaxThumbnailPixels caps the images thumbnails are made of, a small file
+// can claim to be a huge image and decoding it would eat all our memory.
+// Twelve megapixel photos still fit, bigger images are posted without a
+// thumbnail.
+const MaxThumbnailPixels = 4096 * 3072
+
+// ThumbnailExtensions maps the types thumbnails are made of to the
+// extension of the thumbnail. GIFs get PNG thumbnails, which keep their
+// transparency but not their animation.
+var ThumbnailExtensions = map[string]string{
+    "image/jpeg": ".jpg",
+    "image/png": ".png",
+    "image/gif": ".png",
+}
+
+// Thumbnail scales src down to fit in a size by size square, keeping its
+// aspect ratio. Each pixel of the thumbnail is the average of the pixels it
+// covers in src. Images that already fit are only copied.
+func Thumbnail(src image.Image, size int) *image.RGBA {
+    b := src.Bounds()
+    w, h := b.Dx(), b.Dy()
+    if w > size || h > size {
+	if w >= h {
+	    w, h = size, h * size / w
+	} else {
+	    w, h = w * size / h, size
+	}
+    }
+    if w < 1 {
+	w = 1
+    }
+    if h < 1 {
+	h = 1
+    }
+
+    // draw has fast paths to RGBA for every type the image packages
+    // decode to, so each row of src is converted on its own and the
+    // averaging below works on raw pixels without a full size copy
+    row := image.NewRGBA(image.Rect(0, 0, b.Dx(), 1))
+    sums := make([]int, w * 4)
+
+    dst := image.NewRGBA(image.Rect(0, 0, w, h))
+    for y := 0; y < h; y++ {
+	y0, y1 := y * b.Dy() / h, (y + 1) * b.Dy() / h
+	if y1 == y0 {
+	    y1++
+	}
+
+	for i := range sums {
+	    sums[i] = 0
+	}
+	for sy := y0; sy < y1; sy++ {
+	    draw.Draw(row, row.Bounds(), src, image.Pt(b.Min.X, b.Min.Y + sy), draw.Src)
+	    for x := 0; x < w; x++ {
+		x0, x1 := x * b.Dx() / w, (x + 1) * b.Dx() / w
+		if x1 == x0 {
+		    x1++
+		}
+		for sx := x0; sx < x1; sx++ {
+		    for c := 0; c < 4; c++ {
+			sums[x * 4 + c] += int(row.Pix[sx * 4 + c])
+		    }
+		}
+	    }
+	}
+
+	for x := 0; x < w; x++ {
+	    x0, x1 := x * b.Dx() / w, (x + 1) * b.Dx() / w
+	    if x1 == x0 {
+		x1++
+	    }
+	    n := (y1 - y0) * (x1 - x0)
+	    for c := 0; c < 4; c++ {
+		dst.Pix[y * dst.Stride + x * 4 + c] = uint8(sums[x * 4 + c] / n)
+	    }
+	}
+    }
+
+    return dst
+}
+
+// EncodeThumbnail reads an image of mimeType from r and writes its
+// thumbnail to w, in the format given by ThumbnailExtensions.
+func EncodeThumbnail(w io.Writer, r io.ReadSeeker, mimeType string) error {
+    ext, ok := ThumbnailExtensions[mimeType]
+    if !ok {
+	return &models.ValidateError{Message: "Thumbnails cannot be made of " + mimeType + " files."}
+    }
+
+    config, _, err := image.DecodeConfig(r)
+    if err != nil {
+	return err
+    }
+    if config.Width * config.Height > MaxThumbnailPixels {
+	return &models.ValidateError{Message: "The image is too large for a thumbnail."}
+    }
+
+    if _, err := r.Seek(0, io.SeekStart); err != nil {
+	return err
+    }
+
+    var src image.Image
+    switch mimeType {
+    case "image/jpeg":
+	src, err = jpeg.Decode(r)
+    case "image/png":
+	src, err = png.Decode(r)
+    case "image/gif":
+	src, err = gif.Decode(r)
+    }
+    if err != nil {
+	return err
+    }
+
+    thumb := Thumbnail(src, ThumbnailSize)
+    if ext == ".jpg" {
+	return jpeg.Encode(w, thumb, &jpeg.Options{Quality: 85})
+    }
+
+    return png.Encode(w, thumb)
+}
+
+// MaxDuplicateWindow is the longest a board can refuse a file that was
+// already posted, in minutes. Files banned by hash are refused forever.
+const MaxDuplicateWindow = 60 * 24 * 365
+
+var hashRegexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// HashUpload returns the hex encoded SHA-256 of an uploaded file.
+func HashUpload(header *multipart.FileHeader) (string, error) {
+    file, err := header.Open()
+    if err != nil {
+	return "", err
+    }
+    defer file.Close()
+
+    hash := sha256.New()
+    if _, err := io.Copy(hash, file); err != nil {
+	return "", err
+    }
+
+    return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ValidateUploadHash refuses a file whose hash is banned, or that was
+// already posted on the board within its duplicate window.
+func ValidateUploadHash(queries *sqlc.Queries, board sqlc.Board, sum string, now time.Time) (models.FormError, error) {
+    error := models.FormError{
+	Bool: false,
+	Message: "",
+	Field: "file",
+    }
+
+    _, err := queries.GetBannedHash(context.Background(), sum)
+    if err == nil {
+	error = models.FormError{
+	    Bool: true,
+	    Message: "This file is banned",
+	    Field: "file",
+	}
+    } else if !errors.Is(err, sql.ErrNoRows) {
+	return error, err
+    }
+
+    if !error.Bool && board.DuplicateWindow > 0 {
+	n, err := queries.CountBoardDuplicates(context.Background(), sqlc.CountBoardDuplicatesParams{
+	    BoardID: board.BoardID,
+	    Sha256: sum,
+	    Date: now.Add(-time.Duration(board.DuplicateWindow) * time.Minute),
+	})
+	if err != nil {
+	    return error, err
+	}
+	if n > 0 {
+	    error = models.FormError{
+		Bool: true,
+		Message: "This file has already been posted on this board",
+		Field: "file",
+	    }
+	}
+    }
+
+    if error.Bool {
+	err := &models.ValidateError{Message: "The file has not passed the required validation rules."}
+	return error, err
+    }
+
+    return error, nil
+}
+
+// ValidateBannedHash checks a hash a moderator wants to ban, which can be
+// given in any case.
+func ValidateBannedHash(sum string, reason string) (sqlc.CreateBannedHashParams, models.FormError, error) {
+    params := sqlc.CreateBannedHashParams{
+	Sha256: strings.ToLower(strings.TrimSpace(sum)),
+	Reason: strings.TrimSpace(reason),
+    }
+    error := models.FormError{
+	Bool: false,
+	Message: "",
+	Field: "ban",
+    }
+
+    if !hashRegexp.MatchString(params.Sha256) {
+	error = models.FormError{
+	    Bool: true,
+	    Message: "A hash must be the 64 hexadecimal characters of a SHA-256",
+	    Field: "ban",
+	}
+    } else if utf8.RuneCountInString(params.Reason) > 255 {
+	error = models.FormError{
+	    Bool: true,
+	    Message: "Reason can have a length of at most 255 characters",
+	    Field: "ban",
+	}
+    }
+
+    if error.Bool {
+	err := &models.ValidateError{Message: "The hash has not passed the required validation rules."}
+	return params, error, err
+    }
+
+    return params, error, nil
+}
+
+// ValidateBoardFiles checks the upload limits of a board, a size of 0
+// turns uploads off and a duplicate window of 0 lets the same file be
+// posted again at any time.
+func ValidateBoardFiles(size string, types string, window string) (sqlc.UpdateBoardFilesParams, models.FormError, error) {
+    params := sqlc.UpdateBoardFilesParams{}
+    error := models.FormError{
+	Bool: false,
+	Message: "",
+	Field: "files",
+    }
+
+    n, err := strconv.Atoi(size)
+    if err != nil || n < 0 || n > MaxFileSize {
+	error = models.FormError{
+	    Bool: true,
+	    Message: "File size must be a number between 0 and " + strconv.Itoa(MaxFileSize),
+	    Field: "files",
+	}
+    }
+    params.MaxFileSize = int32(n)
+
+    minutes, err := strconv.Atoi(window)
+    if err != nil || minutes < 0 || minutes > MaxDuplicateWindow {
+	error = models.FormError{
+	    Bool: true,
+	    Message: "Duplicate window must be a number of minutes between 0 and " + strconv.Itoa(MaxDuplicateWindow),
+	    Field: "files",
+	}
+    }
+    params.DuplicateWindow = int32(minutes)
+
+    list := splitDomains(types)
+    for _, t := range list {
+	if _, ok := FileExtensions[t]; !ok {
+	    error = models.FormError{
+		Bool: true,
+		Message: "\"" + t + "\" is not a supported file type",
+		Field: "files",
+	    }
+	}
+    }
+    params.AllowedTypes = strings.Join(list, ", ")
+
+    if error.Bool {
+	err := &models.ValidateError{Message: "The file limits have not passed the required validation rules."}
+	return params, error, err
+    }
+
+    return params, error, nil
+}
+
+// MaxNameLength is the longest name a poster can give, in characters.
+const MaxNameLength = 50
+
+// tripcodeSalt is mixed into every tripcode, so they cannot be looked up in
+// tables made for other sites.
+const tripcodeSalt = "gomsg tripcode "
+
+// Tripcode hashes the secret of a name#secret into the code shown after the
+// name, so a poster can prove who they are without registering.
+func Tripcode(secret string) string {
+    sum := sha256.Sum256([]byte(tripcodeSalt + secret))
+    return base64.RawURLEncoding.EncodeToString(sum[:])[:10]
+}
+
+// ParseName splits the name field of a post into the name and the tripcode
+// of the secret after the first #, if there is one. Names longer than
+// MaxNameLength are cut.
+func ParseName(value string) (string, string) {
+    name, secret, _ := strings.Cut(value, "#")
+    name = strings.TrimSpace(name)
+    if utf8.RuneCountInString(name) > MaxNameLength {
+	name = string([]rune(name)[:MaxNameLength])
+    }
+
+    if secret == "" {
+	return name, ""
+    }
+
+    return name, Tripcode(secret)
+}
+
+// PosterID derives the ID a poster is shown with inside one thread from
+// their address. It stays the same for the whole thread but differs from
+// thread to thread, and without the secret it cannot be traced back to the
+// address.
+func PosterID(secret string, ip string, threadID int32) string {
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write([]byte(ip + "/" + strconv.Itoa(int(threadID))))
+    return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))[:8]
+}
+
+// HashPassword salts and hashes the delete password of a post as the hex
+// encoded salt and SHA-256 of the salt and password, joined by a $. An
+// empty password gives an empty hash, the post cannot be deleted then.
+func HashPassword(password string) (string, error) {
+    if password == "" {
+	return "", nil
+    }
+
+    salt := make([]byte, 16)
+    if _, err := rand.Read(salt); err != nil {
+	return "", err
+    }
+
+    sum := sha256.Sum256(append(salt, password...))
+    return hex.EncodeToString(salt) + "$" + hex.EncodeToString(sum[:]), nil
+}
+
+// CheckPassword reports whether password is the one hashed by HashPassword.
+func CheckPassword(hash string, password string) bool {
+    encodedSalt, encodedSum, ok := strings.Cut(hash, "$")
+    if !ok || password == "" {
+	return false
+    }
+
+    salt, err := hex.DecodeString(encodedSalt)
+    if err != nil {
+	return false
+    }
+    want, err := hex.DecodeString(encodedSum)
+    if err != nil {
+	return false
+    }
+
+    sum := sha256.Sum256(append(salt, password...))
+    return subtle.ConstantTimeCompare(sum[:], want) == 1
 }
 
-func ValidatePost(title string, comment string) ([2]models.FormError, error) {
-    errors := [2]models.FormError{
-	{
-	    Bool: false,
-	    Message: "",
-	    Field: "title",
-	},
-	{
-	    Bool: false,
-	    Message: "",
-	    Field: "comment",
-	},
-    }
+// MaxRateBurst is the most posts a board can let a poster make in a row.
+const MaxRateBurst = 100
 
-    if strings.TrimSpace(title) == "" {
-	errors[0] = models.FormError{
-	    Bool: true,
-	    Message: "This field is required",
-	    Field: "title",
-	}
+// MaxRateInterval is the longest a board can make a poster wait for the
+// next post, in seconds.
+const MaxRateInterval = 60 * 60
 
+// TakeToken refills a token bucket that held tokens at last with one token
+// every interval, up to burst, and takes a token from it if there is one.
+// It returns the tokens left in the bucket at now and whether the post is
+// allowed. An interval of 0 turns the limit off.
+func TakeToken(tokens float64, last time.Time, now time.Time, burst int32, interval int32) (float64, bool) {
+    if interval <= 0 {
+	return float64(burst), true
     }
 
-    if strings.TrimSpace(comment) == "" {
-	errors[1] = models.FormError{
-	    Bool: true,
-	    Message: "This field is required",
-	    Field: "comment",
-	}
-
+    if elapsed := now.Sub(last); elapsed > 0 {
+	tokens += elapsed.Seconds() / float64(interval)
+    }
+    if tokens > float64(burst) {
+	tokens = float64(burst)
     }
 
-    if errors[0].Bool || errors[1].Bool {
-	err := &models.ValidateError{Message: "One of the fields has not passed the required validation rules."}
-	return errors, err
+    if tokens < 1 {
+	return tokens, false
     }
 
-    return errors, nil
+    return tokens - 1, true
 }
 
-func ValidateReply(title string) (models.FormError, error) {
+// ValidateBoardRates checks the rate limits of a board. Each limit is a
+// burst of posts a poster can make at once and the seconds it takes for
+// one more to be allowed, an interval of 0 turns the limit off.
+func ValidateBoardRates(threadBurst string, threadInterval string, replyBurst string, replyInterval string) (sqlc.UpdateBoardRatesParams, models.FormError, error) {
+    params := sqlc.UpdateBoardRatesParams{}
     error := models.FormError{
 	Bool: false,
 	Message: "",
-	Field: "comment",
+	Field: "rates",
     }
 
-    if strings.TrimSpace(title) == "" {
-	error = models.FormError{
-	    Bool: true,
-	    Message: "This field is required",
-	    Field: "comment",
-	}
+    values := []struct{
+	value string
+	min int
+	max int
+	message string
+	dst *int32
+    }{
+	{threadBurst, 1, MaxRateBurst, "Thread burst", &params.ThreadBurst},
+	{threadInterval, 0, MaxRateInterval, "Thread interval in seconds", &params.ThreadInterval},
+	{replyBurst, 1, MaxRateBurst, "Reply burst", &params.ReplyBurst},
+	{replyInterval, 0, MaxRateInterval, "Reply interval in seconds", &params.ReplyInterval},
+    }
 
+    for _, v := range values {
+	n, err := strconv.Atoi(v.value)
+	if err != nil || n < v.min || n > v.max {
+	    error = models.FormError{
+		Bool: true,
+		Message: v.message + " must be a number between " + strconv.Itoa(v.min) + " and " + strconv.Itoa(v.max),
+		Field: "rates",
+	    }
+	}
+	*v.dst = int32(n)
     }
 
     if error.Bool {
-	err := &models.ValidateError{Message: "The field has not passed the required validation rules."}
-	return error, err
+	err := &models.ValidateError{Message: "The rate limits have not passed the required validation rules."}
+	return params, error, err
     }
 
-    return error, nil
+    return params, error, nil
 }
 
+// csrfSalt is mixed into every CSRF signature, so a token can never pass as
+// anything else signed with the same secret.
+const csrfSalt = "gomsg csrf "
 
-func GetBoardData(queries *sqlc.Queries, id int32, name string) (models.BoardData, error){
-    threads, err := queries.GetBoardThreads(context.Background(), id)
+func csrfSignature(secret string, nonce string) []byte {
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write([]byte(csrfSalt + nonce))
+    return mac.Sum(nil)
+}
+
+// NewCSRFToken makes a random token signed with the secret, the hex
+// encoded nonce and signature joined by a dot. The same token is set as a
+// cookie and put in every form, see CheckCSRFToken.
+func NewCSRFToken(secret string) (string, error) {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+	return "", err
+    }
+
+    nonce := hex.EncodeToString(b)
+    return nonce + "." + hex.EncodeToString(csrfSignature(secret, nonce)), nil
+}
+
+// ValidCSRFToken reports whether token was made by NewCSRFToken with the
+// secret.
+func ValidCSRFToken(secret string, token string) bool {
+    nonce, encodedSum, ok := strings.Cut(token, ".")
+    if !ok || nonce == "" {
+	return false
+    }
+
+    sum, err := hex.DecodeString(encodedSum)
+    if err != nil {
+	return false
+    }
+
+    return hmac.Equal(sum, csrfSignature(secret, nonce))
+}
+
+// CheckCSRFToken reports whether the token sent with a form is the one in
+// the cookie of the request and was signed with the secret. Another site
+// can make a browser send the cookie along with its form, but it cannot
+// read the cookie to put the token in the form too. The signature only
+// shows the token was made by this server, it is not tied to the visitor,
+// so anyone able to set cookies for our host can fetch a token from any of
+// our forms and plant it along with a form of their own.
+func CheckCSRFToken(secret string, cookie string, field string) bool {
+    if field == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(field)) != 1 {
+	return false
+    }
+
+    return ValidCSRFToken(secret, cookie)
+}
+
+// GetPageNumber parses the page query parameter, pages start at 1 and a
+// missing parameter is the first page.
+func GetPageNumber(value string) (int, error) {
+    if value == "" {
+	return 1, nil
+    }
+
+    page, err := strconv.Atoi(value)
+    if err != nil || page < 1 {
+	err := &models.PathError{Message: "Not found"}
+	return 0, err
+    }
+
+    return page, nil
+}
+
+// GetPage works out the links around page number of a listing with total
+// items. Pages past the end are not found, except for the first page of an
+// empty listing.
+func GetPage(number int, size int32, total int64) (models.Page, error) {
+    page := models.Page{
+	Number: number,
+	Previous: 0,
+	Next: 0,
+    }
+
+    if number > 1 && int64(number - 1) * int64(size) >= total {
+	err := &models.PathError{Message: "Not found"}
+	return page, err
+    }
+
+    if number > 1 {
+	page.Previous = number - 1
+    }
+    if int64(number) * int64(size) < total {
+	page.Next = number + 1
+    }
+
+    return page, nil
+}
+
+// GetIndexData gets a page of the recent activity feed, the newest threads
+// and replies across all boards merged by date.
+func GetIndexData(queries *sqlc.Queries, number int, size int32) (models.IndexData, error){
+    data := models.IndexData{
+	Posts: []models.RecentPost{},
+	Page: models.Page{},
+	Boards: []sqlc.Board{},
+    }
+
+    boards, err := queries.ListBoards(context.Background())
+    if err != nil {
+	return data, err
+    }
+    data.Boards = boards
+
+    nthreads, err := queries.CountThreads(context.Background())
+    if err != nil {
+	return data, err
+    }
+
+    nreplies, err := queries.CountAliveReplies(context.Background())
+    if err != nil {
+	return data, err
+    }
+
+    page, err := GetPage(number, size, nthreads + nreplies)
+    if err != nil {
+	return data, err
+    }
+    data.Page = page
+
+    // whatever ends up on this page is among the newest number * size
+    // threads and replies, so that many of each is enough to merge
+    n := int32(number) * size
+
+    threads, err := queries.GetRecentThreads(context.Background(), n)
+    if err != nil {
+	return data, err
+    }
+
+    replies, err := queries.GetRecentReplies(context.Background(), n)
     if err != nil {
-	data := models.BoardData{
-	    Threads: []sqlc.Thread{},
-	    Name: name,
-	}
 	return data, err
     }
 
+    posts := []models.RecentPost{}
+    for _, thread := range threads {
+	posts = append(posts, models.RecentPost{
+	    ThreadID: thread.ThreadID,
+	    ReplyID: 0,
+	    Title: thread.Title,
+	    Comment: thread.Comment,
+	    Date: thread.Date,
+	    BoardName: thread.BoardName,
+	})
+    }
+    for _, reply := range replies {
+	posts = append(posts, models.RecentPost{
+	    ThreadID: reply.ThreadID,
+	    ReplyID: reply.ReplyID,
+	    Title: reply.Title,
+	    Comment: reply.Comment,
+	    Date: reply.Date,
+	    BoardName: reply.BoardName,
+	})
+    }
+
+    sort.SliceStable(posts, func(i, j int) bool {
+	return posts[i].Date.After(posts[j].Date)
+    })
+
+    start := int(number - 1) * int(size)
+    if start > len(posts) {
+	start = len(posts)
+    }
+    end := start + int(size)
+    if end > len(posts) {
+	end = len(posts)
+    }
+    data.Posts = posts[start:end]
+
+    return data, nil
+}
+
+// GetBoardData gets a page of the board with the last previews replies of
+// each thread on it.
+func GetBoardData(queries *sqlc.Queries, board sqlc.Board, number int, size int32, previews int32) (models.BoardData, error){
     data := models.BoardData{
-	Threads: threads,
-	Name: name,
+	Threads: []models.ThreadPreview{},
+	Attachments: map[int32][]sqlc.Attachment{},
+	Quotes: map[int32][]sqlc.Quote{},
+	Board: board,
+	Page: models.Page{},
+	Boards: []sqlc.Board{},
+    }
+
+    boards, err := queries.ListBoards(context.Background())
+    if err != nil {
+	return data, err
+    }
+    data.Boards = boards
+
+    total, err := queries.CountBoardThreads(context.Background(), board.BoardID)
+    if err != nil {
+	return data, err
+    }
+
+    page, err := GetPage(number, size, total)
+    if err != nil {
+	return data, err
+    }
+    data.Page = page
+
+    threads, err := queries.GetBoardThreads(context.Background(), sqlc.GetBoardThreadsParams{
+	BoardID: board.BoardID,
+	Limit: size,
+	Offset: int32(number - 1) * size,
+    })
+    if err != nil {
+	return data, err
+    }
+
+    // one query for the replies of every thread on the page instead of
+    // one per thread
+    replies, err := queries.GetBoardPreviews(context.Background(), sqlc.GetBoardPreviewsParams{
+	BoardID: board.BoardID,
+	Limit: size,
+	Offset: int32(number - 1) * size,
+	Previews: int64(previews),
+    })
+    if err != nil {
+	return data, err
+    }
+
+    attachments, err := queries.GetBoardAttachments(context.Background(), sqlc.GetBoardAttachmentsParams{
+	BoardID: board.BoardID,
+	Limit: size,
+	Offset: int32(number - 1) * size,
+    })
+    if err != nil {
+	return data, err
+    }
+
+    quotes, err := queries.GetBoardQuotes(context.Background(), sqlc.GetBoardQuotesParams{
+	BoardID: board.BoardID,
+	Limit: size,
+	Offset: int32(number - 1) * size,
+    })
+    if err != nil {
+	return data, err
+    }
+
+    byThread := map[int32][]sqlc.GetBoardPreviewsRow{}
+    for _, reply := range replies {
+	byThread[reply.ThreadID] = append(byThread[reply.ThreadID], reply)
+    }
+
+    opAttachments := map[int32][]sqlc.Attachment{}
+    for _, attachment := range attachments {
+	if attachment.ReplyID.Valid {
+	    data.Attachments[attachment.ReplyID.Int32] = append(data.Attachments[attachment.ReplyID.Int32], attachment)
+	    continue
+	}
+	opAttachments[attachment.ThreadID] = append(opAttachments[attachment.ThreadID], attachment)
+    }
+
+    opQuotes := map[int32][]sqlc.Quote{}
+    for _, quote := range quotes {
+	if quote.ReplyID.Valid {
+	    data.Quotes[quote.ReplyID.Int32] = append(data.Quotes[quote.ReplyID.Int32], quote)
+	    continue
+	}
+	opQuotes[quote.ThreadID] = append(opQuotes[quote.ThreadID], quote)
+    }
+
+    for _, thread := range threads {
+	preview := models.ThreadPreview{
+	    Thread: thread,
+	    Attachments: opAttachments[thread.ThreadID],
+	    Quotes: opQuotes[thread.ThreadID],
+	    Replies: byThread[thread.ThreadID],
+	    Omitted: 0,
+	}
+	if len(preview.Replies) > 0 {
+	    preview.Omitted = preview.Replies[0].ReplyTotal - int64(len(preview.Replies))
+	}
+	data.Threads = append(data.Threads, preview)
+    }
+
+    return data, nil
+}
+
+// CatalogSorts are the orders the catalog can be sorted in, the first one
+// is the default.
+var CatalogSorts = []string{"bump", "date", "replies"}
+
+func GetCatalogData(queries *sqlc.Queries, board sqlc.Board, sortBy string) (models.CatalogData, error){
+    data := models.CatalogData{
+	Threads: []sqlc.GetBoardCatalogRow{},
+	Board: board,
+	Sort: CatalogSorts[0],
+	Sorts: CatalogSorts,
+	Boards: []sqlc.Board{},
     }
 
+    boards, err := queries.ListBoards(context.Background())
+    if err != nil {
+	return data, err
+    }
+    data.Boards = boards
+
+    // the query already returns the threads in bump order
+    threads, err := queries.GetBoardCatalog(context.Background(), board.BoardID)
+    if err != nil {
+	return data, err
+    }
+
+    switch sortBy {
+    case "date":
+	sort.SliceStable(threads, func(i, j int) bool {
+	    return threads[i].Date.After(threads[j].Date)
+	})
+	data.Sort = sortBy
+    case "replies":
+	sort.SliceStable(threads, func(i, j int) bool {
+	    return threads[i].ReplyCount > threads[j].ReplyCount
+	})
+	data.Sort = sortBy
+    }
+    data.Threads = threads
+
+    return data, nil
+}
+
+func GetArchiveData(queries *sqlc.Queries, board sqlc.Board, number int, size int32) (models.ArchiveData, error){
+    data := models.ArchiveData{
+	Threads: []sqlc.Thread{},
+	Board: board,
+	Page: models.Page{},
+	Boards: []sqlc.Board{},
+    }
+
+    boards, err := queries.ListBoards(context.Background())
+    if err != nil {
+	return data, err
+    }
+    data.Boards = boards
+
+    total, err := queries.CountBoardArchivedThreads(context.Background(), board.BoardID)
+    if err != nil {
+	return data, err
+    }
+
+    page, err := GetPage(number, size, total)
+    if err != nil {
+	return data, err
+    }
+    data.Page = page
+
+    threads, err := queries.GetBoardArchivedThreads(context.Background(), sqlc.GetBoardArchivedThreadsParams{
+	BoardID: board.BoardID,
+	Limit: size,
+	Offset: int32(number - 1) * size,
+    })
+    if err != nil {
+	return data, err
+    }
+    data.Threads = threads
+
     return data, nil
 }
 
@@ -112,6 +1415,12 @@ func GetThreadData(queries *sqlc.Queries, id int32) (models.ThreadData, error){
     errdata := models.ThreadData{
 	Op: sqlc.Thread{},
 	Replies: []sqlc.Reply{},
+	Boards: []sqlc.Board{},
+    }
+
+    boards, err := queries.ListBoards(context.Background())
+    if err != nil {
+	return errdata, err
     }
 
     thread, err := queries.GetThread(context.Background(), id)
@@ -119,14 +1428,80 @@ func GetThreadData(queries *sqlc.Queries, id int32) (models.ThreadData, error){
 	return errdata, err
     }
 
+    if thread.Status == sqlc.ThreadsStatusDeleted {
+	err := &models.PathError{Message: "Not found"}
+	return errdata, err
+    }
+
     replies, err := queries.GetThreadReplies(context.Background(), id)
     if err != nil {
 	return errdata, err
     }
 
+    board, err := queries.GetBoard(context.Background(), thread.BoardID)
+    if err != nil {
+	return errdata, err
+    }
+
+    quotes, err := queries.GetThreadQuotes(context.Background(), id)
+    if err != nil {
+	return errdata, err
+    }
+
+    backlinks, err := queries.GetThreadBacklinks(context.Background(), id)
+    if err != nil {
+	return errdata, err
+    }
+
+    attachments, err := queries.GetThreadAttachments(context.Background(), id)
+    if err != nil {
+	return errdata, err
+    }
+
     data := models.ThreadData{
 	Op: thread,
 	Replies: replies,
+	Quotes: map[int32][]sqlc.Quote{},
+	OpQuotes: []sqlc.Quote{},
+	Backlinks: map[int32][]sqlc.GetThreadBacklinksRow{},
+	OpBacklinks: []sqlc.GetThreadBacklinksRow{},
+	Attachments: map[int32][]sqlc.Attachment{},
+	OpAttachments: []sqlc.Attachment{},
+	PosterCounts: map[string]int{},
+	Board: board,
+	Archived: board.Archived || thread.Status != sqlc.ThreadsStatusAlive,
+	Boards: boards,
+    }
+
+    for _, quote := range quotes {
+	if !quote.ReplyID.Valid {
+	    data.OpQuotes = append(data.OpQuotes, quote)
+	    continue
+	}
+	data.Quotes[quote.ReplyID.Int32] = append(data.Quotes[quote.ReplyID.Int32], quote)
+    }
+    for _, backlink := range backlinks {
+	if !backlink.QuotedReplyID.Valid {
+	    data.OpBacklinks = append(data.OpBacklinks, backlink)
+	    continue
+	}
+	data.Backlinks[backlink.QuotedReplyID.Int32] = append(data.Backlinks[backlink.QuotedReplyID.Int32], backlink)
+    }
+    for _, attachment := range attachments {
+	if !attachment.ReplyID.Valid {
+	    data.OpAttachments = append(data.OpAttachments, attachment)
+	    continue
+	}
+	data.Attachments[attachment.ReplyID.Int32] = append(data.Attachments[attachment.ReplyID.Int32], attachment)
+    }
+
+    if thread.PosterID != "" {
+	data.PosterCounts[thread.PosterID]++
+    }
+    for _, reply := range replies {
+	if reply.PosterID != "" && !reply.Deleted {
+	    data.PosterCounts[reply.PosterID]++
+	}
     }
 
     return data, nil
@@ -134,11 +1509,41 @@ func GetThreadData(queries *sqlc.Queries, id int32) (models.ThreadData, error){
 
 func CreateErrorData(status int) models.ErrorData{
     switch status {
+    case http.StatusBadRequest:
+	return models.ErrorData{
+	    Status: status,
+	    Message: "Bad request",
+	}
     case http.StatusNotFound:
 	return models.ErrorData{
 	    Status: status,
 	    Message: "Not found",
 	}
+    case http.StatusUnauthorized:
+	return models.ErrorData{
+	    Status: status,
+	    Message: "Unauthorized",
+	}
+    case http.StatusForbidden:
+	return models.ErrorData{
+	    Status: status,
+	    Message: "Forbidden",
+	}
+    case http.StatusGone:
+	return models.ErrorData{
+	    Status: status,
+	    Message: "This thread has already died",
+	}
+    case http.StatusRequestEntityTooLarge:
+	return models.ErrorData{
+	    Status: status,
+	    Message: "This file is too large",
+	}
+    case http.StatusTooManyRequests:
+	return models.ErrorData{
+	    Status: status,
+	    Message: "You are posting too fast, wait a moment and try again",
+	}
     default:
 	return models.ErrorData{
 	    Status: http.StatusInternalServerError,
@@ -147,17 +1552,34 @@ func CreateErrorData(status int) models.ErrorData{
     }
 }
 
+// ErrorStatus picks the status of the error page a failed lookup should
+// redirect to: missing rows are a 404, anything else is on us.
+func ErrorStatus(err error) int {
+    var pathErr *models.PathError
+    var statusErr *models.StatusError
+    if errors.Is(err, sql.ErrNoRows) || errors.As(err, &pathErr) {
+	return http.StatusNotFound
+    }
+    if errors.As(err, &statusErr) {
+	return statusErr.Status
+    }
+    return http.StatusInternalServerError
+}
+
 type PathInfo struct {
     BoardName string
     ThreadID int
     Status int
+    Subpage string
 }
 
+// PathWant picks the value GetPathValues reads from the path, Subpage
+// additionally allows a page below it such as /board/{name}/catalog.
 type PathWant struct {
     BoardName bool
     ThreadID bool
     Status bool
-
+    Subpage bool
 }
 
 func GetPathValues(ps []string, pw PathWant) (PathInfo, error){
@@ -165,10 +1587,17 @@ func GetPathValues(ps []string, pw PathWant) (PathInfo, error){
 	BoardName: "",
 	ThreadID: 0,
 	Status: 0,
+	Subpage: "",
     }
 
-    if len(ps) > 3 {
-	if ps[3] != "" {
+    last := 3
+    if pw.Subpage && len(ps) > 3 {
+	r.Subpage = ps[3]
+	last = 4
+    }
+
+    if len(ps) > last {
+	if ps[last] != "" {
 	    err := &models.PathError{Message: "Not found"}
 	    return r, err
 	}
@@ -176,7 +1605,7 @@ func GetPathValues(ps []string, pw PathWant) (PathInfo, error){
 
     if pw.BoardName == true {
 	name := ps[2]
-	if name != "sports" && name != "random" && name != "tech" {
+	if name == "" {
 	    err := &models.PathError{Message: "Not found"}
 	    return r, err
 	}
@@ -202,22 +1631,6 @@ func GetPathValues(ps []string, pw PathWant) (PathInfo, error){
     return r, err
 }
 
-func GetBoardID(name string) int32{
-    var id int32
-
-    switch name {
-    case "sports":
-	id = 1;
-    case "random":
-	id = 2;
-    case "tech":
-	id = 3;
-    }
-
-    return id
-}
-
-
 
 
 
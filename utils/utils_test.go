@@ -0,0 +1,447 @@
+package utils
+
+import (
+    "bytes"
+    "database/sql"
+    "html/template"
+    "image"
+    "image/color"
+    "image/gif"
+    "image/png"
+    "mime/multipart"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/enzdor/gomsg/sqlc"
+)
+
+func TestRenderComment(t *testing.T) {
+    quotes := []sqlc.Quote{
+	{ThreadID: 3, ReplyID: sql.NullInt32{Int32: 10, Valid: true}, QuotedThreadID: 4, QuotedReplyID: sql.NullInt32{Int32: 5, Valid: true}},
+	{ThreadID: 3, ReplyID: sql.NullInt32{Int32: 10, Valid: true}, QuotedThreadID: 7, QuotedReplyID: sql.NullInt32{}, QuotedBoard: "tech"},
+    }
+    board := sqlc.Board{Name: "tech", LinksEnabled: true, DeniedDomains: "bad.com"}
+
+    testCases := []struct {
+	name    string
+	comment string
+	want    template.HTML
+    }{
+	{name: "plain", comment: "just some text", want: "just some text"},
+	{name: "escaped", comment: "<script>alert('x')</script>", want: "&lt;script&gt;alert(&#39;x&#39;)&lt;/script&gt;"},
+	{name: "newlines", comment: "one\r\ntwo\nthree", want: "one<br>two<br>three"},
+	{name: "greentext", comment: ">be me\nnot green > here", want: "<span class=\"greentext\">&gt;be me</span><br>not green &gt; here"},
+	{name: "quote", comment: ">>5 is right", want: "<a class=\"quote\" href=\"/thread/4#r5\">&gt;&gt;5</a> is right"},
+	{name: "thread quote", comment: "see >>>/tech/7", want: "see <a class=\"quote\" href=\"/thread/7\">&gt;&gt;&gt;/tech/7</a>"},
+	{name: "thread quote of another board", comment: "see >>>/b/7", want: "see &gt;&gt;&gt;/b/7"},
+	{name: "unknown quote", comment: ">>6 is not stored", want: "&gt;&gt;6 is not stored"},
+	{name: "greentext unknown quote", comment: ">>>6", want: "<span class=\"greentext\">&gt;&gt;&gt;6</span>"},
+	{name: "bold and italics", comment: "**bold** and *italic*", want: "<strong>bold</strong> and <em>italic</em>"},
+	{name: "lone asterisks", comment: "2 * 3 * 4 and a*", want: "2 * 3 * 4 and a*"},
+	{name: "spoiler", comment: "[spoiler]it was him[/spoiler]", want: "<span class=\"spoiler\">it was him</span>"},
+	{name: "spoiler with markup", comment: "[spoiler]**<b>**[/spoiler]", want: "<span class=\"spoiler\"><strong>&lt;b&gt;</strong></span>"},
+	{name: "unclosed spoiler", comment: "[spoiler]oops", want: "[spoiler]oops"},
+	{name: "markup across a quote", comment: "*a >>5 b*", want: "*a <a class=\"quote\" href=\"/thread/4#r5\">&gt;&gt;5</a> b*"},
+	{name: "code block", comment: "look\n```\n<b>**not bold**</b>\n>not green\n```\nafter", want: "look<pre><code>&lt;b&gt;**not bold**&lt;/b&gt;\n&gt;not green</code></pre>after"},
+	{name: "unclosed code block", comment: "```\nx < y", want: "<pre><code>x &lt; y</code></pre>"},
+	{name: "attribute injection", comment: "*\" onmouseover=\"alert(1)*", want: "<em>&#34; onmouseover=&#34;alert(1)</em>"},
+	{name: "link", comment: "see https://go.dev/doc.", want: "see <a href=\"https://go.dev/doc\" rel=\"nofollow ugc noopener\" target=\"_blank\">https://go.dev/doc</a>."},
+	{name: "link with markup", comment: "**http://a.com/*x*?q=1&r=\"2\"**", want: "**<a href=\"http://a.com/&#42;x&#42;?q=1&amp;r=\" rel=\"nofollow ugc noopener\" target=\"_blank\">http://a.com/&#42;x&#42;?q=1&amp;r=</a>&#34;2&#34;**"},
+	{name: "link in spoiler", comment: "[spoiler]http://a.com[/spoiler]", want: "<span class=\"spoiler\"><a href=\"http://a.com\" rel=\"nofollow ugc noopener\" target=\"_blank\">http://a.com</a></span>"},
+	{name: "denied link", comment: "http://www.bad.com/x", want: "http://www.bad.com/x"},
+	{name: "javascript link", comment: "javascript:alert(1)//http://", want: "javascript:alert(1)//http://"},
+    }
+
+    for _, tc := range testCases {
+	t.Run(tc.name, func(t *testing.T) {
+	    got := RenderComment(tc.comment, quotes, board)
+	    if got != tc.want {
+		t.Errorf("expected %q, got %q", tc.want, got)
+	    }
+	})
+    }
+}
+
+func TestParseQuotes(t *testing.T) {
+    testCases := []struct {
+	name    string
+	comment string
+	want    []QuoteRef
+    }{
+	{name: "none", comment: "no quotes here > at all", want: []QuoteRef{}},
+	{name: "reply", comment: ">>12", want: []QuoteRef{{ReplyID: 12}}},
+	{name: "thread", comment: ">>>/tech/3", want: []QuoteRef{{Board: "tech", ThreadID: 3}}},
+	{name: "duplicates", comment: ">>12 >>12 >>13", want: []QuoteRef{{ReplyID: 12}, {ReplyID: 13}}},
+	{name: "too large", comment: ">>99999999999", want: []QuoteRef{}},
+    }
+
+    for _, tc := range testCases {
+	t.Run(tc.name, func(t *testing.T) {
+	    got := ParseQuotes(tc.comment)
+	    if len(got) != len(tc.want) {
+		t.Fatalf("expected %v, got %v", tc.want, got)
+	    }
+	    for i := range got {
+		if got[i] != tc.want[i] {
+		    t.Errorf("expected %v, got %v", tc.want, got)
+		}
+	    }
+	})
+    }
+}
+
+func TestLinkAllowed(t *testing.T) {
+    testCases := []struct {
+	name  string
+	board sqlc.Board
+	url   string
+	want  bool
+    }{
+	{name: "enabled", board: sqlc.Board{LinksEnabled: true}, url: "https://go.dev", want: true},
+	{name: "disabled", board: sqlc.Board{LinksEnabled: false}, url: "https://go.dev", want: false},
+	{name: "allowed", board: sqlc.Board{LinksEnabled: true, AllowedDomains: "go.dev, github.com"}, url: "https://pkg.go.dev/x", want: true},
+	{name: "not allowed", board: sqlc.Board{LinksEnabled: true, AllowedDomains: "go.dev"}, url: "https://notgo.dev", want: false},
+	{name: "denied", board: sqlc.Board{LinksEnabled: true, DeniedDomains: "bad.com"}, url: "http://BAD.com:8080/", want: false},
+	{name: "no host", board: sqlc.Board{LinksEnabled: true}, url: "http://", want: false},
+    }
+
+    for _, tc := range testCases {
+	t.Run(tc.name, func(t *testing.T) {
+	    if got := LinkAllowed(tc.board, tc.url); got != tc.want {
+		t.Errorf("expected %v, got %v", tc.want, got)
+	    }
+	})
+    }
+}
+
+func TestValidateBoardLinks(t *testing.T) {
+    params, _, err := ValidateBoardLinks(true, "Go.dev  github.com,", "")
+    if err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+    if params.AllowedDomains != "go.dev, github.com" || params.DeniedDomains != "" {
+	t.Errorf("expected domains to be normalised, got %q and %q", params.AllowedDomains, params.DeniedDomains)
+    }
+
+    if _, error, err := ValidateBoardLinks(true, "", "http://bad.com"); err == nil || !error.Bool {
+	t.Errorf("expected a URL to be rejected as a domain")
+    }
+}
+
+func fileHeader(t *testing.T, name string, content []byte) *multipart.FileHeader {
+    var buff bytes.Buffer
+    mw := multipart.NewWriter(&buff)
+    fw, err := mw.CreateFormFile("file", name)
+    if err != nil {
+	t.Fatalf("expected no error, got %v", err)
+    }
+    fw.Write(content)
+    mw.Close()
+
+    form, err := multipart.NewReader(&buff, mw.Boundary()).ReadForm(1 << 20)
+    if err != nil {
+	t.Fatalf("expected no error, got %v", err)
+    }
+
+    return form.File["file"][0]
+}
+
+func TestValidateUpload(t *testing.T) {
+    png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 100)...)
+    board := sqlc.Board{MaxFileSize: 1024, AllowedTypes: "image/png, image/jpeg"}
+
+    testCases := []struct {
+	name     string
+	file     string
+	content  []byte
+	board    sqlc.Board
+	mimeType string
+    }{
+	{name: "png", file: "a.png", content: png, board: board, mimeType: "image/png"},
+	{name: "png with another extension", file: "a.txt", content: png, board: board, mimeType: "image/png"},
+	{name: "text named as png", file: "a.png", content: []byte("<script>alert(1)</script>"), board: board},
+	{name: "too large", file: "a.png", content: append(png, make([]byte, 1024)...), board: board},
+	{name: "uploads off", file: "a.png", content: png, board: sqlc.Board{AllowedTypes: "image/png"}},
+    }
+
+    for _, tc := range testCases {
+	mimeType, error, err := ValidateUpload(fileHeader(t, tc.file, tc.content), tc.board)
+	if tc.mimeType == "" {
+	    if err == nil || !error.Bool {
+		t.Errorf("%s: expected the file to be rejected", tc.name)
+	    }
+	    continue
+	}
+	if err != nil || mimeType != tc.mimeType {
+	    t.Errorf("%s: expected %q, got %q and %v", tc.name, tc.mimeType, mimeType, err)
+	}
+    }
+}
+
+func TestValidateBoardFiles(t *testing.T) {
+    params, _, err := ValidateBoardFiles("2048", "Image/PNG  image/gif,", "60")
+    if err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+    if params.MaxFileSize != 2048 || params.AllowedTypes != "image/png, image/gif" || params.DuplicateWindow != 60 {
+	t.Errorf("expected limits to be normalised, got %d, %q and %d", params.MaxFileSize, params.AllowedTypes, params.DuplicateWindow)
+    }
+
+    if _, error, err := ValidateBoardFiles("2048", "image/svg+xml", "0"); err == nil || !error.Bool {
+	t.Errorf("expected an unsupported type to be rejected")
+    }
+    if _, error, err := ValidateBoardFiles("-1", "", "0"); err == nil || !error.Bool {
+	t.Errorf("expected a negative size to be rejected")
+    }
+    if _, error, err := ValidateBoardFiles("2048", "", "-5"); err == nil || !error.Bool {
+	t.Errorf("expected a negative duplicate window to be rejected")
+    }
+}
+
+func TestThumbnail(t *testing.T) {
+    src := image.NewNRGBA(image.Rect(0, 0, 400, 100))
+    for y := 0; y < 100; y++ {
+	for x := 0; x < 400; x++ {
+	    c := color.NRGBA{R: 255, A: 255}
+	    if x >= 200 {
+		c = color.NRGBA{B: 255, A: 255}
+	    }
+	    src.Set(x, y, c)
+	}
+    }
+
+    thumb := Thumbnail(src, ThumbnailSize)
+    if b := thumb.Bounds(); b.Dx() != 250 || b.Dy() != 62 {
+	t.Errorf("expected a 250x62 thumbnail, got %v", b)
+    }
+    if r, _, b, _ := thumb.At(0, 0).RGBA(); r >> 8 != 255 || b != 0 {
+	t.Errorf("expected the left of the thumbnail to stay red")
+    }
+    if r, _, b, _ := thumb.At(249, 61).RGBA(); r != 0 || b >> 8 != 255 {
+	t.Errorf("expected the right of the thumbnail to stay blue")
+    }
+
+    if b := Thumbnail(image.NewNRGBA(image.Rect(0, 0, 30, 20)), ThumbnailSize).Bounds(); b.Dx() != 30 || b.Dy() != 20 {
+	t.Errorf("expected a small image to keep its size, got %v", b)
+    }
+}
+
+func TestEncodeThumbnail(t *testing.T) {
+    var src bytes.Buffer
+    img := image.NewPaletted(image.Rect(0, 0, 100, 600), color.Palette{color.Black, color.White})
+    if err := gif.Encode(&src, img, nil); err != nil {
+	t.Fatalf("expected no error, got %v", err)
+    }
+
+    var dst bytes.Buffer
+    if err := EncodeThumbnail(&dst, bytes.NewReader(src.Bytes()), "image/gif"); err != nil {
+	t.Fatalf("expected no error, got %v", err)
+    }
+
+    config, err := png.DecodeConfig(&dst)
+    if err != nil || config.Width != 41 || config.Height != 250 {
+	t.Errorf("expected a 41x250 png thumbnail, got %v and %v", config, err)
+    }
+
+    if err := EncodeThumbnail(&dst, bytes.NewReader([]byte("not an image")), "image/png"); err == nil {
+	t.Errorf("expected a broken image to fail")
+    }
+}
+
+func TestHashUpload(t *testing.T) {
+    sum, err := HashUpload(fileHeader(t, "a.txt", []byte("abc")))
+    if err != nil || sum != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+	t.Errorf("expected the SHA-256 of abc, got %q and %v", sum, err)
+    }
+}
+
+func TestValidateBannedHash(t *testing.T) {
+    params, _, err := ValidateBannedHash(" BA7816BF8F01CFEA414140DE5DAE2223B00361A396177A9CB410FF61F20015AD ", "spam")
+    if err != nil || params.Sha256 != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+	t.Errorf("expected the hash to be normalised, got %q and %v", params.Sha256, err)
+    }
+
+    if _, error, err := ValidateBannedHash("ba7816bf", ""); err == nil || !error.Bool {
+	t.Errorf("expected a short hash to be rejected")
+    }
+}
+
+func TestCheckPassword(t *testing.T) {
+    hash, err := HashPassword("hunter2")
+    if err != nil {
+	t.Fatalf("expected no error, got %v", err)
+    }
+    other, _ := HashPassword("hunter2")
+    if hash == other {
+	t.Errorf("expected every hash to get its own salt")
+    }
+
+    testCases := []struct {
+	name     string
+	hash     string
+	password string
+	want     bool
+    }{
+	{name: "right password", hash: hash, password: "hunter2", want: true},
+	{name: "wrong password", hash: hash, password: "hunter3", want: false},
+	{name: "empty password", hash: hash, password: "", want: false},
+	{name: "post without a password", hash: "", password: "hunter2", want: false},
+	{name: "broken hash", hash: "zz$zz", password: "hunter2", want: false},
+    }
+
+    for _, tc := range testCases {
+	if got := CheckPassword(tc.hash, tc.password); got != tc.want {
+	    t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+	}
+    }
+
+    if empty, err := HashPassword(""); err != nil || empty != "" {
+	t.Errorf("expected an empty password to give an empty hash, got %q and %v", empty, err)
+    }
+}
+
+func TestParseName(t *testing.T) {
+    trip := Tripcode("secret")
+    if len(trip) != 10 || trip == Tripcode("Secret") {
+	t.Errorf("expected a 10 character tripcode that depends on the secret, got %q", trip)
+    }
+
+    testCases := []struct {
+	name     string
+	value    string
+	want     string
+	tripcode string
+    }{
+	{name: "empty", value: "", want: "", tripcode: ""},
+	{name: "name", value: " anon ", want: "anon", tripcode: ""},
+	{name: "name and secret", value: "anon#secret", want: "anon", tripcode: trip},
+	{name: "only secret", value: "#secret", want: "", tripcode: trip},
+	{name: "empty secret", value: "anon#", want: "anon", tripcode: ""},
+	{name: "long name", value: strings.Repeat("a", 60), want: strings.Repeat("a", MaxNameLength), tripcode: ""},
+    }
+
+    for _, tc := range testCases {
+	name, tripcode := ParseName(tc.value)
+	if name != tc.want || tripcode != tc.tripcode {
+	    t.Errorf("%s: expected %q and %q, got %q and %q", tc.name, tc.want, tc.tripcode, name, tripcode)
+	}
+    }
+}
+
+func TestPosterID(t *testing.T) {
+    id := PosterID("secret", "192.0.2.1", 10)
+    if len(id) != 8 || id != PosterID("secret", "192.0.2.1", 10) {
+	t.Errorf("expected a stable 8 character ID, got %q", id)
+    }
+
+    testCases := []struct {
+	name     string
+	secret   string
+	ip       string
+	threadID int32
+    }{
+	{name: "another poster", secret: "secret", ip: "192.0.2.2", threadID: 10},
+	{name: "another thread", secret: "secret", ip: "192.0.2.1", threadID: 11},
+	{name: "another secret", secret: "other", ip: "192.0.2.1", threadID: 10},
+    }
+
+    for _, tc := range testCases {
+	if PosterID(tc.secret, tc.ip, tc.threadID) == id {
+	    t.Errorf("%s: expected the ID to change", tc.name)
+	}
+    }
+}
+
+func TestTakeToken(t *testing.T) {
+    start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+    tokens, last := 3.0, start
+    for i := 0; i < 3; i++ {
+	var ok bool
+	if tokens, ok = TakeToken(tokens, last, start, 3, 10); !ok {
+	    t.Fatalf("expected post %d of the burst to be allowed", i + 1)
+	}
+    }
+    if _, ok := TakeToken(tokens, last, start, 3, 10); ok {
+	t.Errorf("expected a post after the burst to be limited")
+    }
+
+    testCases := []struct {
+	name     string
+	elapsed  time.Duration
+	interval int32
+	want     bool
+    }{
+	{name: "half an interval", elapsed: 5 * time.Second, interval: 10, want: false},
+	{name: "a whole interval", elapsed: 10 * time.Second, interval: 10, want: true},
+	{name: "clock going back", elapsed: -time.Hour, interval: 10, want: false},
+	{name: "no limit", elapsed: 0, interval: 0, want: true},
+    }
+
+    for _, tc := range testCases {
+	if _, ok := TakeToken(tokens, last, start.Add(tc.elapsed), 3, tc.interval); ok != tc.want {
+	    t.Errorf("%s: expected %v, got %v", tc.name, tc.want, ok)
+	}
+    }
+
+    if tokens, _ := TakeToken(0, start, start.Add(time.Hour), 3, 10); tokens != 2 {
+	t.Errorf("expected the bucket to refill no further than the burst, got %v tokens left", tokens)
+    }
+}
+
+func TestValidateBoardRates(t *testing.T) {
+    params, _, err := ValidateBoardRates("2", "600", "10", "0")
+    if err != nil {
+	t.Errorf("expected no error, got %v", err)
+    }
+    if params.ThreadBurst != 2 || params.ThreadInterval != 600 || params.ReplyBurst != 10 || params.ReplyInterval != 0 {
+	t.Errorf("expected the limits to be kept, got %+v", params)
+    }
+
+    if _, error, err := ValidateBoardRates("0", "600", "10", "0"); err == nil || !error.Bool {
+	t.Errorf("expected a burst of 0 to be rejected")
+    }
+    if _, error, err := ValidateBoardRates("2", "600", "10", "a"); err == nil || !error.Bool {
+	t.Errorf("expected a non numeric interval to be rejected")
+    }
+    if _, error, err := ValidateBoardRates("2", "3601", "10", "0"); err == nil || !error.Bool {
+	t.Errorf("expected an interval over the maximum to be rejected")
+    }
+}
+
+func TestCheckCSRFToken(t *testing.T) {
+    token, err := NewCSRFToken("secret")
+    if err != nil {
+	t.Fatalf("expected no error, got %v", err)
+    }
+    other, err := NewCSRFToken("secret")
+    if err != nil {
+	t.Fatalf("expected no error, got %v", err)
+    }
+    forged, err := NewCSRFToken("not the secret")
+    if err != nil {
+	t.Fatalf("expected no error, got %v", err)
+    }
+
+    testCases := []struct {
+	name   string
+	cookie string
+	field  string
+	want   bool
+    }{
+	{name: "matching token", cookie: token, field: token, want: true},
+	{name: "missing field", cookie: token, field: "", want: false},
+	{name: "missing cookie", cookie: "", field: token, want: false},
+	{name: "another token", cookie: token, field: other, want: false},
+	{name: "another secret", cookie: forged, field: forged, want: false},
+	{name: "unsigned token", cookie: "abc", field: "abc", want: false},
+	{name: "tampered nonce", cookie: "x" + token[1:], field: "x" + token[1:], want: false},
+    }
+
+    for _, tc := range testCases {
+	if got := CheckCSRFToken("secret", tc.cookie, tc.field); got != tc.want {
+	    t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+	}
+    }
+}